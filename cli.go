@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// runCommand runs a command line command and returns the exit code, e.g.
+// gocd-jira-release-notes lint JI-1234
+// gocd-jira-release-notes lint -jql 'project = JI AND status = "Ready for Release"'
+// gocd-jira-release-notes watch
+// gocd-jira-release-notes generate -pipeline iotic-webbing -counter latest-passed
+func runCommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: gocd-jira-release-notes [serve|lint|watch|generate] [flags]")
+		return 2
+	}
+
+	switch args[0] {
+	case "serve":
+		Serve()
+		return 0
+	case "lint":
+		return runLintCommand(NewDefaultConfig(), args[1:], stdout, stderr)
+	case "watch":
+		return runWatchCommand(NewDefaultConfig(), args[1:], stdout, stderr)
+	case "generate":
+		return runGenerateCommand(NewDefaultConfig(), args[1:], stdout, stderr)
+	}
+	fmt.Fprintf(stderr, "unknown command %s\n", args[0])
+	return 2
+}
+
+// runLintCommand exits with 1 when the release notes have problems
+func runLintCommand(cfg *Config, args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	jql := flags.String("jql", "", "lint the issues found by the JQL query")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 && *jql == "" {
+		fmt.Fprintln(stderr, "usage: gocd-jira-release-notes lint [-jql JQL] [KEY...]")
+		return 2
+	}
+
+	report := &LintReport{Passed: true, Issues: []LintIssueReport{}}
+	lint := func(key string, jql string) error {
+		result, err := lintJiraIssues(cfg, key, jql)
+		if err != nil {
+			return err
+		}
+		report.Passed = report.Passed && result.Passed
+		report.Issues = append(report.Issues, result.Issues...)
+		return nil
+	}
+	for _, key := range flags.Args() {
+		err = lint(key, "")
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+	if *jql != "" {
+		err = lint("", *jql)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+
+	printLintReport(stdout, report)
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
+
+// runWatchCommand polls GoCD until it's stopped, or once with -once (exits with 1 when the poll fails)
+func runWatchCommand(cfg *Config, args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	once := flags.Bool("once", false, "poll once and exit")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if len(cfg.Watch.Pipelines) == 0 {
+		fmt.Fprintln(stderr, "set watch.pipelines in the config")
+		return 2
+	}
+
+	watcher, err := newWatcher(cfg, createReleaseNotes, stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if !*once {
+		watcher.watch(nil)
+		return 0
+	}
+	_, err = watcher.poll()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runGenerateCommand creates the release notes of a run like the HTTP endpoint and prints the result,
+// the counter can be a number, latest or latest-passed (exits with 1 when it fails)
+func runGenerateCommand(cfg *Config, args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pipeline := flags.String("pipeline", "", "the GoCD pipeline")
+	counter := flags.String("counter", "", "the run of the pipeline: a counter, latest or latest-passed")
+	label := flags.String("label", "", "the label of the run, instead of the counter")
+	title := flags.String("title", "", "the title of the release notes")
+	approved := flags.Bool("approved", false, "release despite a failed gate")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	logger := newRequestLogger()
+	query := url.Values{}
+	query.Set("pipeline", *pipeline)
+	query.Set("counter", *counter)
+	query.Set("label", *label)
+	query.Set("title", *title)
+	if *approved {
+		query.Set("approved", "true")
+	}
+	queryParams, err := getQueryParamsFromRequest(logger, query)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		fmt.Fprintln(stderr, "usage: gocd-jira-release-notes generate -pipeline PIPELINE [-counter N|latest|latest-passed] [-label LABEL] [-title TITLE] [-approved]")
+		return 2
+	}
+
+	result, err := createReleaseNotes(cfg, queryParams)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(result)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
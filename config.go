@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -20,6 +21,73 @@ type Config struct {
 	JiraUser           string
 	JiraApiKey         string
 	ConfluenceSpaceKey string
+	// Title is the default title of the release notes, when the query string has none
+	Title string
+	// ConfluenceLabels are added to the label with the name of the pipeline
+	ConfluenceLabels []string
+	// ConfluenceAutoLabels are added from the release: version, minor, year, components and breaking-change
+	ConfluenceAutoLabels []string
+	// ConfluenceContentProperty is the key of a content property with the metadata of the release
+	ConfluenceContentProperty string
+	// ConfluenceUpdateExisting updates the blog post (or page) with the same title
+	// instead of creating a new one, when the release notes have changed
+	ConfluenceUpdateExisting bool
+	// ConfluenceMarkers wraps the generated content in anchors,
+	// so that an update keeps the manual edits outside of them
+	ConfluenceMarkers bool
+	// ConfluenceManifest attaches a manifest of the release in each format, json and/or csv
+	ConfluenceManifest []string
+	// ConfluenceTitle overrides the title template of ConfluenceTemplates, see VariantConfig
+	ConfluenceTitle string
+	// ConfluenceTemplates customise the blog posts per pipeline
+	ConfluenceTemplates []ConfluenceTemplate
+	// ConfluenceContentType is either "blogpost" (default) or "page";
+	// pages are created under the parent page and the (templated) parent path
+	ConfluenceContentType  string
+	ConfluenceParentPageID string
+	ConfluenceParentPath   string
+	// Publishers limits where the release notes are published,
+	// e.g. [confluence, slack]; all the configured publishers by default
+	Publishers []string
+	// JiraReleaseNotesField is the custom field containing the release notes
+	JiraReleaseNotesField string
+	// RequireStage is the stage of the pipeline which has to pass before publishing, e.g. deploy-prod;
+	// a running stage is waited for up to RequireStageTimeout, otherwise the response is 409
+	RequireStage        string
+	RequireStageTimeout time.Duration
+	// ReleaseDateStage dates the release by the completion of the jobs of the stage, e.g. deploy-prod,
+	// rather than by when the pipeline was scheduled; Timezone formats the dates, e.g. Europe/London
+	ReleaseDateStage string
+	Timezone         string
+	// JiraCommentOnRelease adds a comment linking to the published
+	// release notes to every Jira issue included in the release
+	JiraCommentOnRelease bool
+	// JiraTransitions are applied to the issues of a published release
+	JiraTransitions       []JiraTransitionRule
+	JiraTransitionsDryRun bool
+	Slack                 SlackConfig
+	Webhooks              []WebhookConfig
+	Email                 EmailConfig
+	Github                GithubConfig
+	Site                  SiteConfig
+	// Gate checks the release notes before publishing, see also /validate
+	Gate GateConfig
+	// Lint checks the release notes of Jira issues, see /lint
+	Lint LintConfig
+	// Redaction strips internal content from the release notes per publisher
+	Redaction []RedactionRules
+	// Variants produce the release notes for each audience, with their own publishers
+	Variants []VariantConfig
+	// Locales publish the release notes of each language to Confluence
+	Locales []LocaleConfig
+	// Jobs run the release notes in the background, see POST /jobs
+	Jobs JobsConfig
+	// Triggers run the release notes on GoCD stage notifications, see POST /notifications
+	Triggers TriggersConfig
+	// Watch polls GoCD for new runs, see the watch command
+	Watch WatchConfig
+	// Pipelines override the settings above per pipeline
+	Pipelines []PipelineConfig
 }
 
 func init() {
@@ -51,6 +119,94 @@ func NewDefaultConfig() *Config {
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
 
+	var transitions []JiraTransitionRule
+	unmarshalConfigKey("jiraTransitions", &transitions)
+
+	var slack SlackConfig
+	unmarshalConfigKey("slack", &slack)
+
+	var webhooks []WebhookConfig
+	unmarshalConfigKey("webhooks", &webhooks)
+	for i, webhook := range webhooks {
+		if webhook.SecretName == "" {
+			continue
+		}
+		webhooks[i].Secret, err = getAPISecret(webhook.SecretName)
+		if err != nil {
+			log.Fatalf("failed to read secret %s: %v", webhook.SecretName, err)
+		}
+		// NOTE: otherwise anyone could sign the release notes with the default secret
+		if !isSecretSet(webhooks[i].Secret) {
+			log.Fatalf("set the secret %s for the signed webhook %s", webhook.SecretName, webhook.Name)
+		}
+	}
+
+	var email EmailConfig
+	unmarshalConfigKey("email", &email)
+	if email.Username != "" {
+		email.Password, err = getAPISecret("smtppassword")
+		if err != nil {
+			log.Fatalf("failed to read secret %s: %v", "smtppassword", err)
+		}
+	}
+
+	var github GithubConfig
+	unmarshalConfigKey("github", &github)
+	if len(github.Routes) > 0 {
+		github.Token, err = getAPISecret("githubtoken")
+		if err != nil {
+			log.Fatalf("failed to read secret %s: %v", "githubtoken", err)
+		}
+	}
+
+	var site SiteConfig
+	unmarshalConfigKey("site", &site)
+
+	var gate GateConfig
+	unmarshalConfigKey("gate", &gate)
+
+	var lint LintConfig
+	unmarshalConfigKey("lint", &lint)
+
+	var redaction []RedactionRules
+	unmarshalConfigKey("redaction", &redaction)
+
+	var variants []VariantConfig
+	unmarshalConfigKey("variants", &variants)
+
+	var locales []LocaleConfig
+	unmarshalConfigKey("locales", &locales)
+
+	var jobs JobsConfig
+	unmarshalConfigKey("jobs", &jobs)
+
+	var watch WatchConfig
+	unmarshalConfigKey("watch", &watch)
+
+	var triggers TriggersConfig
+	unmarshalConfigKey("triggers", &triggers)
+	if triggers.Signed {
+		triggers.Secret, err = getAPISecret("gocdnotificationsecret")
+		if err != nil {
+			log.Fatalf("failed to read secret %s: %v", "gocdnotificationsecret", err)
+		}
+		// NOTE: otherwise anyone could sign the notifications with the default secret
+		if !isSecretSet(triggers.Secret) {
+			log.Fatalf("set the secret %s for the signed notifications", "gocdnotificationsecret")
+		}
+	}
+
+	var confluenceTemplates []ConfluenceTemplate
+	unmarshalConfigKey("confluenceTemplates", &confluenceTemplates)
+
+	var pipelines []PipelineConfig
+	unmarshalConfigKey("pipelines", &pipelines)
+
+	jiraReleaseNotesField := viper.GetString("jiraReleaseNotesField")
+	if jiraReleaseNotesField == "" {
+		jiraReleaseNotesField = defaultJiraReleaseNotesField
+	}
+
 	return &Config{
 		Client:             &http.Client{Transport: transport},
 		Port:               viper.GetString("port"),
@@ -60,9 +216,60 @@ func NewDefaultConfig() *Config {
 		JiraUser:           viper.GetString("jiraUser"), // NOTE: change to your email address for development
 		JiraApiKey:         jiraApiKey,                  // NOTE: change to your JIRA password during development
 		ConfluenceSpaceKey: viper.GetString("confluenceSpaceKey"),
+
+		Title:                     viper.GetString("title"),
+		ConfluenceLabels:          viper.GetStringSlice("confluenceLabels"),
+		ConfluenceAutoLabels:      viper.GetStringSlice("confluenceAutoLabels"),
+		ConfluenceContentProperty: viper.GetString("confluenceContentProperty"),
+		ConfluenceUpdateExisting:  viper.GetBool("confluenceUpdateExisting"),
+		ConfluenceMarkers:         viper.GetBool("confluenceMarkers"),
+		ConfluenceManifest:        viper.GetStringSlice("confluenceManifest"),
+		ConfluenceTemplates:       confluenceTemplates,
+		ConfluenceContentType:     viper.GetString("confluenceContentType"),
+		ConfluenceParentPageID:    viper.GetString("confluenceParentPageId"),
+		ConfluenceParentPath:      viper.GetString("confluenceParentPath"),
+		Publishers:                viper.GetStringSlice("publishers"),
+		JiraReleaseNotesField:     jiraReleaseNotesField,
+		RequireStage:              viper.GetString("requireStage"),
+		RequireStageTimeout:       viper.GetDuration("requireStageTimeout"),
+		ReleaseDateStage:          viper.GetString("releaseDateStage"),
+		Timezone:                  viper.GetString("timezone"),
+		JiraCommentOnRelease:      viper.GetBool("jiraCommentOnRelease"),
+		JiraTransitions:           transitions,
+		JiraTransitionsDryRun:     viper.GetBool("jiraTransitionsDryRun"),
+		Slack:                     slack,
+		Webhooks:                  webhooks,
+		Email:                     email,
+		Github:                    github,
+		Site:                      site,
+		Gate:                      gate,
+		Lint:                      lint,
+		Redaction:                 redaction,
+		Variants:                  variants,
+		Locales:                   locales,
+		Jobs:                      jobs,
+		Triggers:                  triggers,
+		Watch:                     watch,
+		Pipelines:                 pipelines,
 	}
 }
 
+// unmarshalConfigKey reads a nested section of the config, e.g. `slack`
+func unmarshalConfigKey(key string, rawVal interface{}) {
+	err := viper.UnmarshalKey(key, rawVal)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", key, err)
+	}
+}
+
+// defaultSecret is returned by getAPISecret when the secret is not set
+const defaultSecret = "notset"
+
+// isSecretSet checks the secret is neither empty nor the default one
+func isSecretSet(secret string) bool {
+	return secret != "" && secret != defaultSecret
+}
+
 func getAPISecret(secretName string) (string, error) {
 	rtn := ""
 
@@ -83,5 +290,5 @@ func getAPISecret(secretName string) (string, error) {
 	}
 
 	fmt.Printf("using default for '%s' - use for testing only\n", secretName)
-	return "notset", nil
+	return defaultSecret, nil
 }
@@ -8,7 +8,6 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
-	"time"
 
 	"gopkg.in/go-playground/validator.v9"
 )
@@ -36,10 +35,15 @@ type ConfluencePost struct {
 	Space  ConfluenceSpace `json:"space"`
 	Status string          `json:"status"`
 	// ID is optional, it could be created to match e.g. YYYYMMDDHHMMSS
-	// ID     string          `json:"id"`
+	// NOTE: only set when updating an existing post
+	ID       string             `json:"id,omitempty"`
 	Title    string             `json:"title"`
 	Body     ConfluenceBody     `json:"body"`
 	Metadata ConfluenceMetadata `json:"metadata"`
+	// Version is required when updating an existing post
+	Version *ConfluenceVersion `json:"version,omitempty"`
+	// Ancestors is the parent page of a page (not used by blog posts)
+	Ancestors []ConfluenceAncestor `json:"ancestors,omitempty"`
 }
 
 type ConfluenceSpace struct {
@@ -48,6 +52,8 @@ type ConfluenceSpace struct {
 
 type ConfluenceBody struct {
 	Storage ConfluenceStorage `json:"storage"`
+	// Editor2 is only returned with ?expand=body.editor2
+	Editor2 *ConfluenceStorage `json:"editor2,omitempty"`
 }
 
 type ConfluenceStorage struct {
@@ -56,11 +62,29 @@ type ConfluenceStorage struct {
 }
 
 type ConfluenceBlogPost struct {
-	ID     string `json:"id"`
-	Type   string `json:"type"`
-	Status string `json:"status"`
-	Title  string `json:"title"`
+	ID      string            `json:"id"`
+	Type    string            `json:"type"`
+	Status  string            `json:"status"`
+	Title   string            `json:"title"`
+	Body    ConfluenceBody    `json:"body"`
+	Version ConfluenceVersion `json:"version"`
+	Links   ConfluenceLinks   `json:"_links"`
 }
+
+type ConfluenceLinks struct {
+	Base  string `json:"base"`
+	WebUI string `json:"webui"`
+}
+
+// URL returns the absolute link to the blog post, e.g.
+// https://<account>.atlassian.net/wiki/spaces/RN/blog/2021/03/26/539492380/Title
+func (p *ConfluenceBlogPost) URL() string {
+	if p.Links.WebUI == "" {
+		return ""
+	}
+	return p.Links.Base + p.Links.WebUI
+}
+
 type ConfluenceMetadata struct {
 	Labels []ConfluenceLabel `json:"labels"`
 }
@@ -69,8 +93,8 @@ type ConfluenceLabel struct {
 	Name string `json:"name"`
 }
 
-func NewConfluencePost(spaceKey string, title string, content string, label string) *ConfluencePost {
-	return &ConfluencePost{
+func NewConfluencePost(spaceKey string, title string, content string, labels ...string) *ConfluencePost {
+	post := &ConfluencePost{
 		Type:   "blogpost",
 		Space:  ConfluenceSpace{Key: spaceKey},
 		Status: "current",
@@ -81,32 +105,97 @@ func NewConfluencePost(spaceKey string, title string, content string, label stri
 				Value:          content,
 			},
 		},
-		Metadata: ConfluenceMetadata{
-			Labels: []ConfluenceLabel{
-				{Name: label},
-			},
-		},
 	}
+	for _, label := range labels {
+		post.Metadata.Labels = append(post.Metadata.Labels, ConfluenceLabel{Name: label})
+	}
+	return post
 }
 
-func publishReleaseNotesToConfluence(cfg *Config, timestamp time.Time, title string, pipeline string, version string, notes *Notes) (*ConfluenceBlogPost, error) {
+// publishReleaseNotesToConfluence creates the blog post (or page),
+// or updates the existing one when ConfluenceUpdateExisting is set
+func publishReleaseNotesToConfluence(cfg *Config, release *Release) (*ConfluenceBlogPost, *ConfluenceUpdate, error) {
+	post, err := newConfluencePostFor(cfg, release)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content/#api-api-content-post
-	apiURL := fmt.Sprintf("%s/wiki/rest/api/content/", cfg.JiraUrl)
+	parentID := ""
+	if post.Type == "page" {
+		path, err := getConfluenceParentPath(cfg.ConfluenceParentPath, newReleaseData(release))
+		if err != nil {
+			return nil, nil, err
+		}
+		parentID, err = ensureConfluenceParent(cfg, cfg.ConfluenceSpaceKey, cfg.ConfluenceParentPageID, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if parentID != "" {
+			post.Ancestors = []ConfluenceAncestor{{ID: parentID}}
+		}
+	}
 
-	log.Printf("Calling %s", apiURL)
+	if cfg.ConfluenceUpdateExisting {
+		existing, err := findConfluencePost(cfg, post, release.Timestamp)
+		if err != nil {
+			return nil, nil, err
+		}
+		if existing != nil {
+			blogPost, update, err := updateConfluencePost(cfg, existing, post)
+			if err != nil || !update.Updated {
+				return blogPost, update, err
+			}
+			return blogPost, update, addConfluenceMetadata(cfg, blogPost, release)
+		}
+	}
+
+	blogPost, err := createConfluencePost(cfg, post)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// NOTE: this is a magical string for the YYYY-MM-DD format
-	date := timestamp.Format("2006-01-02")
+	err = addConfluenceMetadata(cfg, blogPost, release)
+	if err != nil {
+		return blogPost, nil, err
+	}
+
+	if parentID != "" {
+		err = sortConfluencePage(cfg, parentID, blogPost)
+		if err != nil {
+			return blogPost, nil, err
+		}
+	}
+	return blogPost, nil, nil
+}
 
-	postTitle := fmt.Sprintf("%s Release Notes %s - %s", title, version, date)
+// newConfluencePostFor renders the blog post (or page) of the release, without its parent page
+func newConfluencePostFor(cfg *Config, release *Release) (*ConfluencePost, error) {
+	data := newReleaseData(release)
+	tmpl := getConfluenceTemplateFor(cfg, release.Pipeline)
+	postTitle, err := createConfluenceTitle(tmpl, data)
+	if err != nil {
+		return nil, err
+	}
 
-	content, err := createConfluenceContentHTML(cfg, notes)
+	content, err := createConfluenceContentHTML(cfg, tmpl, data)
 	if err != nil {
 		return nil, err
 	}
 
-	post := NewConfluencePost(cfg.ConfluenceSpaceKey, postTitle, content, pipeline)
+	post := NewConfluencePost(cfg.ConfluenceSpaceKey, postTitle, content, createConfluenceLabels(cfg, release)...)
+	if cfg.ConfluenceContentType == "page" {
+		post.Type = "page"
+	}
+	return post, nil
+}
+
+func createConfluencePost(cfg *Config, post *ConfluencePost) (*ConfluenceBlogPost, error) {
+
+	// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content/#api-api-content-post
+	apiURL := fmt.Sprintf("%s/wiki/rest/api/content/", cfg.JiraUrl)
+
+	log.Printf("Calling %s", apiURL)
+
 	jsonStr, _ := json.Marshal(post)
 
 	req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewBuffer(jsonStr))
@@ -130,22 +219,39 @@ func publishReleaseNotesToConfluence(cfg *Config, timestamp time.Time, title str
 	// NOTE: for debugging
 	// os.WriteFile("./sample-data/confluence-post.json", response, 0644)
 
-	blogPost, err := parseConfluenceBlogPost(response)
-	if err != nil {
-		return nil, err
-	}
-	return blogPost, nil
+	return parseConfluenceBlogPost(response)
 }
 
-func createConfluenceContentHTML(cfg *Config, notes *Notes) (string, error) {
-	var buf bytes.Buffer
-	for k, v := range notes.Groups {
-		buf.WriteString(fmt.Sprintf("\nh1. %s\n", k))
-		for _, l := range v {
-			buf.Write([]byte(l + "\n"))
+// addConfluenceMetadata adds the content property and the manifest attachments, if configured
+func addConfluenceMetadata(cfg *Config, blogPost *ConfluenceBlogPost, release *Release) error {
+	if cfg.ConfluenceContentProperty != "" {
+		err := setConfluenceContentProperty(cfg, blogPost.ID, cfg.ConfluenceContentProperty, newReleaseProperty(release))
+		if err != nil {
+			return err
+		}
+	}
+	for _, format := range cfg.ConfluenceManifest {
+		name, content, err := createReleaseManifest(release, format)
+		if err != nil {
+			return err
+		}
+		err = attachConfluenceFile(cfg, blogPost.ID, name, content)
+		if err != nil {
+			return err
 		}
 	}
-	result := buf.Bytes()
+	return nil
+}
+
+func createConfluenceContentHTML(cfg *Config, tmpl ConfluenceTemplate, data *ReleaseData) (string, error) {
+	wikiMarkup, err := createConfluenceWikiMarkup(tmpl, data)
+	if err != nil {
+		return "", err
+	}
+	if cfg.ConfluenceMarkers {
+		wikiMarkup = wrapWithMarkers(wikiMarkup)
+	}
+	result := []byte(wikiMarkup)
 
 	// NOTE: I've tried this approach initially,
 	// but it didn't work well with JIRA/Confluence URL format or JIRA/Confluence macros
@@ -216,7 +322,6 @@ func convertToConfluenceFormat(cfg *Config, text []byte) ([]byte, error) {
 }
 
 func validateConfluenceStorage(json ConfluenceStorage) error {
-	validate = validator.New()
 	err := validate.Struct(json)
 	if err != nil {
 
@@ -252,7 +357,6 @@ func parseConfluenceStorage(jsonData []byte) (ConfluenceStorage, error) {
 }
 
 func validateConfluenceBlogPost(json ConfluenceBlogPost) error {
-	validate = validator.New()
 	err := validate.Struct(json)
 	if err != nil {
 
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// the labels which can be added automatically, see Config.ConfluenceAutoLabels
+const (
+	autoLabelVersion        = "version"         // e.g. release-2-0-390
+	autoLabelMinor          = "minor"           // e.g. release-2-0
+	autoLabelYear           = "year"            // e.g. 2021
+	autoLabelComponents     = "components"      // the Jira components of the issues
+	autoLabelBreakingChange = "breaking-change" // when there is a "Breaking Change(s)" group
+)
+
+// NOTE: Confluence does not allow spaces and some punctuation in labels, e.g. "2.0" is invalid
+var invalidLabelChars = regexp.MustCompile(`[\s:;,.?&\[\]()#^*@!<>'"/\\|{}~=+%$]+`)
+
+func sanitizeConfluenceLabel(label string) string {
+	return strings.Trim(invalidLabelChars.ReplaceAllString(strings.ToLower(label), "-"), "-")
+}
+
+// isBreakingChangeGroup matches both "Breaking Change" and "Breaking Changes"
+func isBreakingChangeGroup(name string) bool {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), "s") == "breaking change"
+}
+
+// createConfluenceLabels returns the pipeline name, the configured labels
+// and the automatic labels, sanitized and without duplicates
+func createConfluenceLabels(cfg *Config, release *Release) []string {
+	labels := append([]string{release.Pipeline}, cfg.ConfluenceLabels...)
+	for _, auto := range cfg.ConfluenceAutoLabels {
+		switch auto {
+		case autoLabelVersion:
+			labels = append(labels, "release-"+release.Version)
+		case autoLabelMinor:
+			parts := strings.Split(extractVersion(release.Version), ".")
+			if len(parts) >= 2 {
+				labels = append(labels, "release-"+parts[0]+"."+parts[1])
+			}
+		case autoLabelYear:
+			labels = append(labels, release.Timestamp.Format("2006"))
+		case autoLabelComponents:
+			for _, issue := range release.Issues {
+				for _, c := range issue.Fields.Components {
+					labels = append(labels, c.Name)
+				}
+			}
+		case autoLabelBreakingChange:
+			for name := range release.Notes.Groups {
+				if isBreakingChangeGroup(name) {
+					labels = append(labels, autoLabelBreakingChange)
+					break
+				}
+			}
+		}
+	}
+
+	result := []string{}
+	for _, label := range labels {
+		label = sanitizeConfluenceLabel(label)
+		if label != "" && !containsString(result, label) {
+			result = append(result, label)
+		}
+	}
+	return result
+}
+
+// ConfluenceContentProperty stores JSON metadata on a blog post or page,
+// which can be queried by CQL, e.g. content.property[release-notes].pipeline = "iotic-webbing"
+type ConfluenceContentProperty struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+	// Version is required when updating an existing property
+	Version *ConfluenceVersion `json:"version,omitempty"`
+}
+
+// ReleaseProperty is the machine-readable metadata of the release notes
+type ReleaseProperty struct {
+	Pipeline    string   `json:"pipeline"`
+	Version     string   `json:"version"`
+	FromCounter int      `json:"fromCounter"`
+	ToCounter   int      `json:"toCounter"`
+	Issues      []string `json:"issues"`
+	Groups      []string `json:"groups"`
+}
+
+func newReleaseProperty(release *Release) *ReleaseProperty {
+	property := &ReleaseProperty{
+		Pipeline:    release.Pipeline,
+		Version:     release.Version,
+		FromCounter: release.Counter,
+		ToCounter:   release.Counter,
+		Issues:      []string{},
+		Groups:      []string{},
+	}
+	if release.Comparison != nil {
+		property.FromCounter = release.Comparison.FromCounter
+		property.ToCounter = release.Comparison.ToCounter
+	}
+	for _, issue := range release.Issues {
+		property.Issues = append(property.Issues, issue.Key)
+	}
+	for _, g := range sortedGroups(release.Notes) {
+		property.Groups = append(property.Groups, g.Name)
+	}
+	return property
+}
+
+// setConfluenceContentProperty adds the property, or updates it when the post already has it
+func setConfluenceContentProperty(cfg *Config, contentID string, key string, value interface{}) error {
+
+	// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content-properties/#api-wiki-rest-api-content-id-property-key-get
+	apiURL := fmt.Sprintf("%s/wiki/rest/api/content/%s/property/%s", cfg.JiraUrl, contentID, url.PathEscape(key))
+
+	var existing ConfluenceContentProperty
+	statusCode, err := callConfluence(cfg, http.MethodGet, apiURL, nil, &existing)
+	if statusCode == http.StatusNotFound {
+		// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content-properties/#api-wiki-rest-api-content-id-property-post
+		apiURL = fmt.Sprintf("%s/wiki/rest/api/content/%s/property", cfg.JiraUrl, contentID)
+		property := &ConfluenceContentProperty{Key: key, Value: value}
+		_, err = callConfluence(cfg, http.MethodPost, apiURL, property, nil)
+		if err != nil {
+			return fmt.Errorf("failed to add content property %s: %w", key, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content-properties/#api-wiki-rest-api-content-id-property-key-put
+	number := 1
+	if existing.Version != nil {
+		number = existing.Version.Number + 1
+	}
+	property := &ConfluenceContentProperty{Key: key, Value: value, Version: &ConfluenceVersion{Number: number}}
+	_, err = callConfluence(cfg, http.MethodPut, apiURL, property, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update content property %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+func TestSanitizeConfluenceLabel(t *testing.T) {
+	type test struct {
+		input string
+		want  string
+	}
+	tests := []test{
+		{input: "iotic-webbing", want: "iotic-webbing"},
+		{input: "release-2.0.390", want: "release-2-0-390"},
+		{input: "Web UI (beta)", want: "web-ui-beta"},
+		{input: "  #tag! ", want: "tag"},
+		{input: "a:b;c,d", want: "a-b-c-d"},
+	}
+
+	for _, tc := range tests {
+		got := sanitizeConfluenceLabel(tc.input)
+		if tc.want != got {
+			t.Fatalf("%s expected: %v, got: %v", tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestCreateConfluenceLabels(t *testing.T) {
+	release := newTestRelease()
+	release.Notes.Groups["Breaking Changes"] = []string{"* BC1"}
+	issue := JiraIssue{Key: "JI-1"}
+	issue.Fields.Components = []JiraComponent{{ID: "1", Name: "Web UI"}}
+	release.Issues = []JiraIssue{issue, issue}
+
+	cfg := &Config{
+		ConfluenceLabels:     []string{"Release Notes", "iotic-webbing"},
+		ConfluenceAutoLabels: []string{"version", "minor", "year", "components", "breaking-change", "unknown"},
+	}
+	want := []string{"iotic-webbing", "release-notes", "release-2-0-390", "release-2-0", "2021", "web-ui", "breaking-change"}
+	got := createConfluenceLabels(cfg, release)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected: %v, got: %v", want, got)
+	}
+
+	cfg.ConfluenceAutoLabels = []string{"breaking-change"}
+	delete(release.Notes.Groups, "Breaking Changes")
+	got = createConfluenceLabels(cfg, release)
+	if containsString(got, "breaking-change") {
+		t.Errorf("unexpected breaking-change label: %v", got)
+	}
+}
+
+func TestPublishAddsContentProperty(t *testing.T) {
+	properties := []ConfluenceContentProperty{}
+	cfg := NewDefaultConfig()
+	cfg.ConfluenceContentProperty = "release-notes"
+	cfg.Client = &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			switch req.URL.Path {
+			case "/wiki/rest/api/contentbody/convert/editor2":
+				return jsonResponse(http.StatusOK, &ConfluenceStorage{Value: "<p>converted</p>", Representation: "editor2"}), nil
+			case "/wiki/rest/api/content/":
+				return jsonResponse(http.StatusOK, &ConfluenceBlogPost{ID: "200", Title: "The Best Web"}), nil
+			case "/wiki/rest/api/content/200/property/release-notes":
+				return jsonResponse(http.StatusNotFound, map[string]string{}), nil
+			case "/wiki/rest/api/content/200/property":
+				var property ConfluenceContentProperty
+				body, _ := ioutil.ReadAll(req.Body)
+				json.Unmarshal(body, &property)
+				properties = append(properties, property)
+			}
+			return jsonResponse(http.StatusOK, map[string]string{}), nil
+		},
+	}
+
+	release := newTestRelease()
+	release.Comparison = &GocdPipelineComparison{FromCounter: 610, ToCounter: 614}
+	release.Issues = []JiraIssue{{Key: "JI-1"}, {Key: "JI-2"}}
+	_, _, err := publishReleaseNotesToConfluence(cfg, release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(properties) != 1 || properties[0].Key != "release-notes" {
+		t.Fatalf("unexpected properties: %+v", properties)
+	}
+	want := map[string]interface{}{
+		"pipeline":    "iotic-webbing",
+		"version":     "2.0.390",
+		"fromCounter": float64(610),
+		"toCounter":   float64(614),
+		"issues":      []interface{}{"JI-1", "JI-2"},
+		"groups":      []interface{}{"Bug Fixes", "Improvements"},
+	}
+	if !reflect.DeepEqual(want, properties[0].Value) {
+		t.Errorf("expected: %v, got: %v", want, properties[0].Value)
+	}
+}
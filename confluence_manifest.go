@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// ReleaseManifest lists everything which went into the release, for traceability
+type ReleaseManifest struct {
+	Title     string
+	Pipeline  string
+	Counter   int
+	Version   string
+	Timestamp time.Time
+	Materials []MaterialData
+	Issues    []IssueData
+}
+
+func newReleaseManifest(release *Release) *ReleaseManifest {
+	data := newReleaseData(release)
+	return &ReleaseManifest{
+		Title:     data.Title,
+		Pipeline:  data.Pipeline,
+		Counter:   data.Counter,
+		Version:   data.Version,
+		Timestamp: data.Timestamp,
+		Materials: data.Materials,
+		Issues:    data.Issues,
+	}
+}
+
+// createReleaseManifest returns the file name and content of the manifest in the format (json or csv)
+func createReleaseManifest(release *Release, format string) (string, []byte, error) {
+	manifest := newReleaseManifest(release)
+	name := fmt.Sprintf("%s-%s-manifest.%s", sitePath(release.Pipeline), sitePath(release.Version), format)
+	switch format {
+	case "json":
+		content, err := json.MarshalIndent(manifest, "", "  ")
+		return name, content, err
+	case "csv":
+		content, err := createManifestCSV(manifest)
+		return name, content, err
+	}
+	return "", nil, fmt.Errorf("unknown manifest format %s", format)
+}
+
+// createManifestCSV writes a row for each Jira issue of each revision,
+// revisions without a Jira issue have empty issue columns
+func createManifestCSV(manifest *ReleaseManifest) ([]byte, error) {
+	issues := map[string]IssueData{}
+	for _, issue := range manifest.Issues {
+		issues[issue.Key] = issue
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"pipeline", "version", "material", "revision", "author", "message", "issue", "type", "status"})
+	for _, m := range manifest.Materials {
+		for _, r := range m.Revisions {
+			keys := findJiraIssueKeys(r.Message)
+			if len(keys) == 0 {
+				keys = []string{""}
+			}
+			for _, key := range keys {
+				issue := issues[key]
+				w.Write([]string{manifest.Pipeline, manifest.Version, m.Description, r.Revision, r.Author, r.Message, key, issue.Type, issue.Status})
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// attachConfluenceFile creates the attachment, or a new version of it if it already exists
+func attachConfluenceFile(cfg *Config, contentID string, name string, content []byte) error {
+
+	// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content---attachments/#api-wiki-rest-api-content-id-child-attachment-put
+	apiURL := fmt.Sprintf("%s/wiki/rest/api/content/%s/child/attachment", cfg.JiraUrl, contentID)
+
+	log.Printf("Calling %s", apiURL)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", name)
+	if err != nil {
+		return err
+	}
+	part.Write(content)
+	writer.WriteField("minorEdit", "true")
+	writer.Close()
+
+	req, err := http.NewRequest(http.MethodPut, apiURL, body)
+	if err != nil {
+		return err
+	}
+
+	// create a token here: https://id.atlassian.com/manage-profile/security/api-tokens
+	req.SetBasicAuth(cfg.JiraUser, cfg.JiraApiKey)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	// NOTE: required by Confluence to accept multipart requests
+	req.Header.Add("X-Atlassian-Token", "no-check")
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		response, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("failed to attach %s: %d %s", name, resp.StatusCode, string(response))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+func newTestManifestRelease(t *testing.T) *Release {
+	release := newTestRelease()
+	release.Comparison = readSampleComparison(t, "./sample-data/gocd-pipeline-compare-short.json")
+	issue := JiraIssue{Key: "JI-2034"}
+	issue.Fields.Issuetype.Name = "Story"
+	issue.Fields.Status.Name = "Done"
+	release.Issues = []JiraIssue{issue}
+	return release
+}
+
+func TestCreateReleaseManifest(t *testing.T) {
+	release := newTestManifestRelease(t)
+
+	name, content, err := createReleaseManifest(release, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "iotic-webbing-2.0.390-manifest.json" {
+		t.Errorf("unexpected name: %v", name)
+	}
+	var manifest ReleaseManifest
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Counter != 614 || len(manifest.Issues) != 1 || manifest.Materials[0].Revisions[0].Revision != "ef66f41856582bc700ef7d08878882315afc9359" {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+
+	_, content, err = createReleaseManifest(release, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(content), "\n")
+	if lines[0] != "pipeline,version,material,revision,author,message,issue,type,status" {
+		t.Errorf("unexpected header: %v", lines[0])
+	}
+	want := `iotic-webbing,2.0.390,"URL: git@github.com:Iotic-Labs/system-core.git, Branch: main",ef66f41856582bc700ef7d08878882315afc9359,This Developer <12345678+this-developer@users.noreply.github.com>,JI-2034 Added host identifiers to health endpoint,JI-2034,Story,Done`
+	if lines[1] != want {
+		t.Errorf("expected: %v, got: %v", want, lines[1])
+	}
+
+	_, _, err = createReleaseManifest(release, "xml")
+	if !ErrorContains(err, "unknown manifest format xml") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestPublishAttachesManifest(t *testing.T) {
+	attachments := map[string]string{}
+	cfg := NewDefaultConfig()
+	cfg.ConfluenceManifest = []string{"json", "csv"}
+	cfg.Client = &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			switch req.URL.Path {
+			case "/wiki/rest/api/content/":
+				return jsonResponse(http.StatusOK, &ConfluenceBlogPost{ID: "200"}), nil
+			case "/wiki/rest/api/content/200/child/attachment":
+				if req.Method != http.MethodPut || req.Header.Get("X-Atlassian-Token") != "no-check" {
+					t.Errorf("unexpected request: %v %v", req.Method, req.Header)
+				}
+				_, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+				reader := multipart.NewReader(req.Body, params["boundary"])
+				for {
+					part, err := reader.NextPart()
+					if err != nil {
+						break
+					}
+					content, _ := ioutil.ReadAll(part)
+					if part.FormName() == "file" {
+						attachments[part.FileName()] = string(content)
+					}
+				}
+			}
+			return jsonResponse(http.StatusOK, &ConfluenceStorage{Value: "<p>converted</p>"}), nil
+		},
+	}
+
+	_, _, err := publishReleaseNotesToConfluence(cfg, newTestManifestRelease(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for name := range attachments {
+		names = append(names, name)
+	}
+	if len(names) != 2 || !strings.Contains(attachments["iotic-webbing-2.0.390-manifest.csv"], "JI-2034,Story,Done") {
+		t.Errorf("unexpected attachments: %v", names)
+	}
+	if !json.Valid([]byte(attachments["iotic-webbing-2.0.390-manifest.json"])) {
+		t.Errorf("invalid JSON manifest")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the generated content is wrapped in anchors, so that an update replaces only
+// the generated region and keeps whatever was edited by hand around it
+const (
+	markerStart = "release-notes-start"
+	markerEnd   = "release-notes-end"
+)
+
+func wrapWithMarkers(wikiMarkup string) string {
+	return fmt.Sprintf("{anchor:%s}\n%s{anchor:%s}\n", markerStart, wikiMarkup, markerEnd)
+}
+
+// findMarkedRegion returns the start of the start anchor macro and the end of
+// the end anchor macro in the Confluence body, e.g.
+// <ac:structured-macro ac:name="anchor"><ac:parameter ac:name="">release-notes-start</ac:parameter></ac:structured-macro>
+func findMarkedRegion(content string) (int, int, bool) {
+	startName := strings.Index(content, ">"+markerStart+"<")
+	if startName < 0 {
+		return 0, 0, false
+	}
+	endName := strings.Index(content[startName:], ">"+markerEnd+"<")
+	if endName < 0 {
+		return 0, 0, false
+	}
+	endName += startName
+
+	start := strings.LastIndex(content[:startName], "<ac:structured-macro")
+	endClose := strings.Index(content[endName:], "</ac:structured-macro>")
+	if start < 0 || endClose < 0 {
+		return 0, 0, false
+	}
+	return start, endName + endClose + len("</ac:structured-macro>"), true
+}
+
+// spliceMarkedContent replaces the marked region of the existing body with the marked
+// region of the new body, it also returns both regions to compare them
+func spliceMarkedContent(existing string, generated string) (content string, oldRegion string, newRegion string, ok bool) {
+	oldStart, oldEnd, ok := findMarkedRegion(existing)
+	if !ok {
+		return "", "", "", false
+	}
+	newStart, newEnd, ok := findMarkedRegion(generated)
+	if !ok {
+		return "", "", "", false
+	}
+	oldRegion = existing[oldStart:oldEnd]
+	newRegion = generated[newStart:newEnd]
+	return existing[:oldStart] + newRegion + existing[oldEnd:], oldRegion, newRegion, true
+}
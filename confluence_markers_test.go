@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+func anchorMacro(name string) string {
+	return `<ac:structured-macro ac:name="anchor" ac:schema-version="1"><ac:parameter ac:name="">` + name + `</ac:parameter></ac:structured-macro>`
+}
+
+func TestWrapWithMarkers(t *testing.T) {
+	got := wrapWithMarkers("h1. Improvements\n* Impr1\n")
+	want := "{anchor:release-notes-start}\nh1. Improvements\n* Impr1\n{anchor:release-notes-end}\n"
+	if want != got {
+		t.Errorf("expected: %q, got: %q", want, got)
+	}
+}
+
+func TestSpliceMarkedContent(t *testing.T) {
+	type test struct {
+		existing string
+		want     string
+		ok       bool
+	}
+	generated := "<p>" + anchorMacro(markerStart) + "</p><h1>Improvements</h1><ul><li>Impr2</li></ul><p>" + anchorMacro(markerEnd) + "</p>"
+	tests := []test{
+		{
+			existing: "<p>Intro written by hand</p><p>" + anchorMacro(markerStart) + "</p><h1>Improvements</h1><ul><li>Impr1</li></ul><p>" + anchorMacro(markerEnd) + "</p><p>Outro</p>",
+			want:     "<p>Intro written by hand</p><p>" + anchorMacro(markerStart) + "</p><h1>Improvements</h1><ul><li>Impr2</li></ul><p>" + anchorMacro(markerEnd) + "</p><p>Outro</p>",
+			ok:       true,
+		},
+		{
+			existing: "<p>Intro</p><h1>Improvements</h1><ul><li>Impr1</li></ul>",
+			ok:       false,
+		},
+		{
+			existing: "<p>" + anchorMacro(markerStart) + "</p><p>the end marker was deleted</p>",
+			ok:       false,
+		},
+	}
+
+	for _, tc := range tests {
+		got, _, _, ok := spliceMarkedContent(tc.existing, generated)
+		if tc.ok != ok || tc.want != got {
+			t.Fatalf("expected: %v %q, got: %v %q", tc.ok, tc.want, ok, got)
+		}
+	}
+}
+
+func TestPublishUpdateKeepsManualEdits(t *testing.T) {
+	updates := []ConfluencePost{}
+	converted := []string{}
+	cfg := NewDefaultConfig()
+	cfg.ConfluenceUpdateExisting = true
+	cfg.ConfluenceMarkers = true
+	cfg.Client = &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			switch {
+			case req.URL.Path == "/wiki/rest/api/contentbody/convert/editor2":
+				var storage ConfluenceStorage
+				body, _ := ioutil.ReadAll(req.Body)
+				json.Unmarshal(body, &storage)
+				converted = append(converted, storage.Value)
+				return jsonResponse(http.StatusOK, &ConfluenceStorage{
+					Value:          anchorMacro(markerStart) + "<h1>Bug Fixes</h1><ul><li>BF1</li></ul><h1>Improvements</h1><ul><li>Impr1</li><li>Impr2</li></ul>" + anchorMacro(markerEnd),
+					Representation: "editor2",
+				}), nil
+			case req.Method == http.MethodGet:
+				existing := ConfluenceBlogPost{ID: "300", Version: ConfluenceVersion{Number: 1}}
+				existing.Body.Editor2 = &ConfluenceStorage{
+					Value:          "<ul><li>Intro bullet written by hand</li></ul>" + anchorMacro(markerStart) + "<h1>Improvements</h1><ul><li>Impr1</li></ul>" + anchorMacro(markerEnd),
+					Representation: "editor2",
+				}
+				return jsonResponse(http.StatusOK, &ConfluenceContentList{Results: []ConfluenceBlogPost{existing}}), nil
+			case req.Method == http.MethodPut:
+				var post ConfluencePost
+				body, _ := ioutil.ReadAll(req.Body)
+				json.Unmarshal(body, &post)
+				updates = append(updates, post)
+				return jsonResponse(http.StatusOK, &ConfluenceBlogPost{ID: "300"}), nil
+			}
+			return jsonResponse(http.StatusOK, map[string]string{}), nil
+		},
+	}
+
+	_, update, err := publishReleaseNotesToConfluence(cfg, newTestRelease())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(converted) != 1 || !strings.HasPrefix(converted[0], "{anchor:release-notes-start}") {
+		t.Errorf("unexpected wiki markup: %v", converted)
+	}
+	if len(updates) != 1 {
+		t.Fatalf("unexpected updates: %+v", updates)
+	}
+	want := "<ul><li>Intro bullet written by hand</li></ul>" + anchorMacro(markerStart) + "<h1>Bug Fixes</h1><ul><li>BF1</li></ul><h1>Improvements</h1><ul><li>Impr1</li><li>Impr2</li></ul>" + anchorMacro(markerEnd)
+	if updates[0].Body.Storage.Value != want {
+		t.Errorf("unexpected body: %v", updates[0].Body.Storage.Value)
+	}
+	// NOTE: the bullet written by hand is not reported as removed
+	if len(update.Diff) != 2 || len(update.Diff[0].Removed) != 0 || len(update.Diff[1].Removed) != 0 {
+		t.Errorf("unexpected diff: %+v", update.Diff)
+	}
+}
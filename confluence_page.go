@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfluenceAncestor is the parent page of a page
+type ConfluenceAncestor struct {
+	ID string `json:"id"`
+}
+
+// ConfluenceContentList represents a page of Confluence search results
+type ConfluenceContentList struct {
+	Results []ConfluenceBlogPost `json:"results"`
+	Start   int                  `json:"start"`
+	Limit   int                  `json:"limit"`
+	Size    int                  `json:"size"`
+}
+
+// NOTE: intermediate pages only list their child pages
+const confluenceChildrenMacro = `<ac:structured-macro ac:name="children" />`
+
+// getConfluenceParentPath renders the configured path of the parent pages,
+// e.g. "Release Notes/{{ .Title }}/{{ .Title }} {{ .Timestamp.Year }}"
+// NOTE: page titles must be unique in a space, so don't use e.g. just the year
+func getConfluenceParentPath(pathTemplate string, data *ReleaseData) ([]string, error) {
+	path, err := renderTextTemplate("parent", pathTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+	titles := []string{}
+	for _, title := range strings.Split(path, "/") {
+		if strings.TrimSpace(title) != "" {
+			titles = append(titles, strings.TrimSpace(title))
+		}
+	}
+	return titles, nil
+}
+
+// ensureConfluenceParent finds (or creates) each page of the path under the parent page
+// and returns the ID of the last one
+func ensureConfluenceParent(cfg *Config, spaceKey string, parentID string, path []string) (string, error) {
+	for _, title := range path {
+		var page *ConfluenceBlogPost
+		var err error
+		if parentID == "" {
+			// NOTE: without a parent page, the first page of the path is looked up in the whole space
+			page, err = findConfluenceContent(cfg, spaceKey, "page", title)
+		} else {
+			page, err = findConfluenceChildPage(cfg, parentID, title)
+		}
+		if err != nil {
+			return "", err
+		}
+		if page == nil {
+			page, err = createConfluencePage(cfg, spaceKey, title, parentID)
+			if err != nil {
+				return "", err
+			}
+		}
+		parentID = page.ID
+	}
+	return parentID, nil
+}
+
+func findConfluenceContent(cfg *Config, spaceKey string, contentType string, title string) (*ConfluenceBlogPost, error) {
+
+	// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content/#api-wiki-rest-api-content-get
+	query := url.Values{}
+	query.Set("spaceKey", spaceKey)
+	query.Set("type", contentType)
+	query.Set("title", title)
+	apiURL := fmt.Sprintf("%s/wiki/rest/api/content?%s", cfg.JiraUrl, query.Encode())
+
+	var list ConfluenceContentList
+	_, err := callConfluence(cfg, http.MethodGet, apiURL, nil, &list)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Results) == 0 {
+		return nil, nil
+	}
+	return &list.Results[0], nil
+}
+
+// findConfluenceChildPage returns the child page of the parent with the title, or nil
+func findConfluenceChildPage(cfg *Config, parentID string, title string) (*ConfluenceBlogPost, error) {
+	children, err := getConfluenceChildPages(cfg, parentID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range children {
+		if children[i].Title == title {
+			return &children[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func createConfluencePage(cfg *Config, spaceKey string, title string, parentID string) (*ConfluenceBlogPost, error) {
+
+	// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content/#api-wiki-rest-api-content-post
+	apiURL := fmt.Sprintf("%s/wiki/rest/api/content/", cfg.JiraUrl)
+
+	page := &ConfluencePost{
+		Type:   "page",
+		Space:  ConfluenceSpace{Key: spaceKey},
+		Status: "current",
+		Title:  title,
+		Body: ConfluenceBody{
+			Storage: ConfluenceStorage{Representation: "storage", Value: confluenceChildrenMacro},
+		},
+	}
+	if parentID != "" {
+		page.Ancestors = []ConfluenceAncestor{{ID: parentID}}
+	}
+
+	var created ConfluenceBlogPost
+	statusCode, err := callConfluence(cfg, http.MethodPost, apiURL, page, &created)
+	if statusCode == http.StatusBadRequest && strings.Contains(err.Error(), "already exists") {
+		return nil, fmt.Errorf("a page titled %s already exists in the space %s, page titles must be unique in a space, "+
+			"e.g. add the title of the release notes to the pages of confluenceParentPath", title, spaceKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page %s: %w", title, err)
+	}
+	return &created, nil
+}
+
+func getConfluenceChildPages(cfg *Config, parentID string) ([]ConfluenceBlogPost, error) {
+	pages := []ConfluenceBlogPost{}
+	for {
+		// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content-child-and-descendants/#api-wiki-rest-api-content-id-child-type-get
+		apiURL := fmt.Sprintf("%s/wiki/rest/api/content/%s/child/page?start=%d&limit=100", cfg.JiraUrl, parentID, len(pages))
+
+		var list ConfluenceContentList
+		_, err := callConfluence(cfg, http.MethodGet, apiURL, nil, &list)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, list.Results...)
+		if list.Size == 0 || list.Size < list.Limit {
+			return pages, nil
+		}
+	}
+}
+
+// sortConfluencePage moves the page among its siblings, so that the children
+// of the parent page stay sorted by version, the newest first
+func sortConfluencePage(cfg *Config, parentID string, page *ConfluenceBlogPost) error {
+	children, err := getConfluenceChildPages(cfg, parentID)
+	if err != nil {
+		return err
+	}
+	siblings := []ConfluenceBlogPost{}
+	for _, c := range children {
+		if c.ID != page.ID {
+			siblings = append(siblings, c)
+		}
+	}
+	if len(siblings) == 0 {
+		return nil
+	}
+	sort.SliceStable(siblings, func(i, j int) bool {
+		return compareVersions(extractVersion(siblings[i].Title), extractVersion(siblings[j].Title)) > 0
+	})
+
+	version := extractVersion(page.Title)
+	for _, sibling := range siblings {
+		if compareVersions(version, extractVersion(sibling.Title)) >= 0 {
+			return moveConfluencePage(cfg, page.ID, "before", sibling.ID)
+		}
+	}
+	return moveConfluencePage(cfg, page.ID, "after", siblings[len(siblings)-1].ID)
+}
+
+func moveConfluencePage(cfg *Config, pageID string, position string, targetID string) error {
+
+	// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content---children-and-descendants/#api-wiki-rest-api-content-pageid-move-position-targetid-put
+	apiURL := fmt.Sprintf("%s/wiki/rest/api/content/%s/move/%s/%s", cfg.JiraUrl, pageID, position, targetID)
+
+	_, err := callConfluence(cfg, http.MethodPut, apiURL, nil, nil)
+	return err
+}
+
+var versionPattern = regexp.MustCompile(`\d+(?:\.\d+)+`)
+
+// extractVersion finds a version such as 2.0.390 in a title
+func extractVersion(title string) string {
+	return versionPattern.FindString(title)
+}
+
+// compareVersions compares dotted versions numerically,
+// it returns -1 if a < b, 0 if a == b and 1 if a > b
+func compareVersions(a string, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numA, numB int
+		if i < len(partsA) {
+			numA, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			numB, _ = strconv.Atoi(partsB[i])
+		}
+		if numA != numB {
+			if numA < numB {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// callConfluence sends the payload as JSON and parses the JSON response into the result
+func callConfluence(cfg *Config, method string, apiURL string, payload interface{}, result interface{}) (int, error) {
+
+	log.Printf("Calling %s %s", method, apiURL)
+
+	body := &bytes.Buffer{}
+	if payload != nil {
+		jsonStr, _ := json.Marshal(payload)
+		body = bytes.NewBuffer(jsonStr)
+	}
+	req, err := http.NewRequest(method, apiURL, body)
+	if err != nil {
+		return 0, err
+	}
+
+	// create a token here: https://id.atlassian.com/manage-profile/security/api-tokens
+	req.SetBasicAuth(cfg.JiraUser, cfg.JiraApiKey)
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	response, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return resp.StatusCode, fmt.Errorf("401 Unauthorized")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%d %s", resp.StatusCode, string(response))
+	}
+	if result != nil {
+		err = json.Unmarshal(response, result)
+	}
+	return resp.StatusCode, err
+}
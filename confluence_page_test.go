@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+func TestCompareVersions(t *testing.T) {
+	type test struct {
+		a    string
+		b    string
+		want int
+	}
+	tests := []test{
+		{a: "1.0.0", b: "1.0.0", want: 0},
+		{a: "1.0", b: "1.0.0", want: 0},
+		{a: "2.0.390", b: "2.0.39", want: 1},
+		{a: "1.9.0", b: "1.10.0", want: -1},
+		{a: "", b: "0.0.1", want: -1},
+	}
+
+	for _, tc := range tests {
+		got := compareVersions(tc.a, tc.b)
+		if tc.want != got {
+			t.Fatalf("%s vs %s expected: %v, got: %v", tc.a, tc.b, tc.want, got)
+		}
+	}
+}
+
+func TestExtractVersion(t *testing.T) {
+	got := extractVersion("The Best Web Release Notes 2.0.390 - 2021-03-10")
+	if got != "2.0.390" {
+		t.Errorf("unexpected version: %v", got)
+	}
+}
+
+func jsonResponse(statusCode int, v interface{}) *http.Response {
+	json, _ := json.Marshal(v)
+	return &http.Response{
+		StatusCode: statusCode,
+		Body:       ioutil.NopCloser(bytes.NewReader(json)),
+	}
+}
+
+func TestPublishPageUnderParentPath(t *testing.T) {
+	requests := []string{}
+	created := []ConfluencePost{}
+	cfg := NewDefaultConfig()
+	cfg.ConfluenceContentType = "page"
+	cfg.ConfluenceParentPageID = "100"
+	cfg.ConfluenceParentPath = "{{ .Title }}/{{ .Title }} {{ .Timestamp.Year }}"
+	cfg.Client = &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			path := req.URL.Path
+			requests = append(requests, req.Method+" "+path+" "+req.URL.Query().Get("title"))
+			switch {
+			case req.Method == http.MethodGet && path == "/wiki/rest/api/content/100/child/page":
+				return jsonResponse(http.StatusOK, &ConfluenceContentList{Limit: 100, Size: 1, Results: []ConfluenceBlogPost{{ID: "101", Title: "The Best Web"}}}), nil
+			case req.Method == http.MethodGet && path == "/wiki/rest/api/content/101/child/page":
+				return jsonResponse(http.StatusOK, &ConfluenceContentList{Limit: 100, Size: 1, Results: []ConfluenceBlogPost{{ID: "103", Title: "The Best Web 2020"}}}), nil
+			case req.Method == http.MethodPost && path == "/wiki/rest/api/contentbody/convert/editor2":
+				return jsonResponse(http.StatusOK, &ConfluenceStorage{Value: "<p>converted</p>", Representation: "editor2"}), nil
+			case req.Method == http.MethodPost && path == "/wiki/rest/api/content/":
+				var post ConfluencePost
+				body, _ := ioutil.ReadAll(req.Body)
+				json.Unmarshal(body, &post)
+				created = append(created, post)
+				if post.Title == "The Best Web 2021" {
+					return jsonResponse(http.StatusOK, &ConfluenceBlogPost{ID: "102", Title: post.Title}), nil
+				}
+				return jsonResponse(http.StatusOK, &ConfluenceBlogPost{ID: "200", Title: post.Title}), nil
+			case req.Method == http.MethodGet && path == "/wiki/rest/api/content/102/child/page":
+				return jsonResponse(http.StatusOK, &ConfluenceContentList{Limit: 100, Size: 3, Results: []ConfluenceBlogPost{
+					{ID: "201", Title: "The Best Web Release Notes 2.0.391 - 2021-03-11"},
+					{ID: "200", Title: "The Best Web Release Notes 2.0.390 - 2021-03-10"},
+					{ID: "199", Title: "The Best Web Release Notes 2.0.389 - 2021-03-09"},
+				}}), nil
+			}
+			return jsonResponse(http.StatusOK, map[string]string{}), nil
+		},
+	}
+
+	post, _, err := publishReleaseNotesToConfluence(cfg, newTestRelease())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.ID != "200" {
+		t.Errorf("unexpected page: %+v", post)
+	}
+
+	want := []string{
+		"POST /wiki/rest/api/contentbody/convert/editor2 ",
+		"GET /wiki/rest/api/content/100/child/page ",
+		"GET /wiki/rest/api/content/101/child/page ",
+		"POST /wiki/rest/api/content/ ",
+		"POST /wiki/rest/api/content/ ",
+		"GET /wiki/rest/api/content/102/child/page ",
+		"PUT /wiki/rest/api/content/200/move/before/199 ",
+	}
+	if !reflect.DeepEqual(want, requests) {
+		t.Errorf("expected: %v, got: %v", want, requests)
+	}
+	if created[0].Type != "page" || !reflect.DeepEqual(created[0].Ancestors, []ConfluenceAncestor{{ID: "101"}}) {
+		t.Errorf("unexpected yearly page: %+v", created[0])
+	}
+	if created[1].Type != "page" || !reflect.DeepEqual(created[1].Ancestors, []ConfluenceAncestor{{ID: "102"}}) {
+		t.Errorf("unexpected release notes page: %+v", created[1])
+	}
+}
+
+func TestCreateConfluencePageWithDuplicateTitle(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Client = &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(http.StatusBadRequest, map[string]interface{}{
+				"statusCode": 400,
+				"message":    "com.atlassian.confluence.api.service.exceptions.BadRequestException: A page with this title already exists: A page already exists with the title 2021 in the space with key BLOG",
+			}), nil
+		},
+	}
+	_, err := createConfluencePage(cfg, "BLOG", "2021", "101")
+	if !ErrorContains(err, "a page titled 2021 already exists in the space BLOG, page titles must be unique in a space") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+)
+
+// NOTE: these match the original hard-coded title and headings
+const (
+	defaultConfluenceTitle = `{{ .Title }} Release Notes {{ .Version }} - {{ .Timestamp.Format "2006-01-02" }}`
+	defaultConfluenceBody  = `{{ range .Groups }}
+h1. {{ .Name }}
+{{ range .BulletPoints }}{{ . }}
+{{ end }}{{ end }}`
+)
+
+// ConfluenceTemplate customises the blog post of a pipeline ("*" for all pipelines);
+// all the templates are Go text/templates producing the Jira wiki markup,
+// so Confluence macros such as {toc} or {info}…{info} can be used too
+type ConfluenceTemplate struct {
+	Pipeline string
+	// Title is an inline template of the blog post title
+	Title string
+	// Body is a path to a template of the body, see templates/confluence-body.wiki.tmpl
+	Body string
+	// Preamble and Footer are inline templates added before and after the body
+	Preamble string
+	Footer   string
+}
+
+// getConfluenceTemplate returns the first template configured for the pipeline
+func getConfluenceTemplate(templates []ConfluenceTemplate, pipeline string) ConfluenceTemplate {
+	for _, t := range templates {
+		if matchPipeline(t.Pipeline, pipeline) {
+			return t
+		}
+	}
+	return ConfluenceTemplate{}
+}
+
+// getConfluenceTemplateFor returns the template of the pipeline, with the title overridden by ConfluenceTitle
+func getConfluenceTemplateFor(cfg *Config, pipeline string) ConfluenceTemplate {
+	tmpl := getConfluenceTemplate(cfg.ConfluenceTemplates, pipeline)
+	if cfg.ConfluenceTitle != "" {
+		tmpl.Title = cfg.ConfluenceTitle
+	}
+	return tmpl
+}
+
+func createConfluenceTitle(t ConfluenceTemplate, data *ReleaseData) (string, error) {
+	text := t.Title
+	if text == "" {
+		text = defaultConfluenceTitle
+	}
+	title, err := renderTextTemplate("title", text, data)
+	return strings.TrimSpace(title), err
+}
+
+// createConfluenceWikiMarkup renders the preamble, body and footer
+func createConfluenceWikiMarkup(t ConfluenceTemplate, data *ReleaseData) (string, error) {
+	body := defaultConfluenceBody
+	if t.Body != "" {
+		text, err := ioutil.ReadFile(t.Body)
+		if err != nil {
+			return "", err
+		}
+		body = string(text)
+	}
+
+	var buf strings.Builder
+	for _, part := range []struct{ name, text string }{
+		{"preamble", t.Preamble},
+		{"body", body},
+		{"footer", t.Footer},
+	} {
+		if part.text == "" {
+			continue
+		}
+		result, err := renderTextTemplate(part.name, part.text, data)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(result + "\n")
+	}
+	if len(data.Translations) > 0 {
+		links := []string{}
+		for _, t := range data.Translations {
+			links = append(links, fmt.Sprintf("[%s|%s]", t.Locale, t.Link))
+		}
+		buf.WriteString(fmt.Sprintf("\nOther languages: %s\n", strings.Join(links, " | ")))
+	}
+	return buf.String(), nil
+}
+
+func renderTextTemplate(name string, text string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	return buf.String(), err
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCreateConfluenceTitle(t *testing.T) {
+	type test struct {
+		template string
+		want     string
+	}
+	tests := []test{
+		{template: "", want: "The Best Web Release Notes 2.0.390 - 2021-03-10"},
+		{template: `{{ .Title }} {{ .Version }} (build {{ .Counter }}) {{ .Timestamp.Format "Jan 2, 2006" }}`, want: "The Best Web 2.0.390 (build 614) Mar 10, 2021"},
+	}
+
+	for _, tc := range tests {
+		got, err := createConfluenceTitle(ConfluenceTemplate{Title: tc.template}, newReleaseData(newTestRelease()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Fatalf("expected: %v, got: %v", tc.want, got)
+		}
+	}
+}
+
+func TestCreateConfluenceWikiMarkup(t *testing.T) {
+	type test struct {
+		template ConfluenceTemplate
+		want     string
+	}
+	tests := []test{
+		{
+			template: ConfluenceTemplate{},
+			want:     "\nh1. Bug Fixes\n* BF1\n\nh1. Improvements\n* Impr1\n* [RPCStatus|https://example.com/rpc]\n\n",
+		},
+		{
+			template: ConfluenceTemplate{Preamble: "{toc}", Footer: "{info}Contact support for {{ .Title }}{info}"},
+			want:     "{toc}\n\nh1. Bug Fixes\n* BF1\n\nh1. Improvements\n* Impr1\n* [RPCStatus|https://example.com/rpc]\n\n{info}Contact support for The Best Web{info}\n",
+		},
+	}
+
+	for _, tc := range tests {
+		got, err := createConfluenceWikiMarkup(tc.template, newReleaseData(newTestRelease()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.want != got {
+			t.Fatalf("expected: %q, got: %q", tc.want, got)
+		}
+	}
+}
+
+func TestCreateConfluenceWikiMarkupFromExampleTemplate(t *testing.T) {
+	release := newTestRelease()
+	release.Comparison = readSampleComparison(t, "./sample-data/gocd-pipeline-compare-short.json")
+	issue := JiraIssue{Key: "JI-2034"}
+	issue.Fields.Summary = "Add host identifiers"
+	issue.Fields.Issuetype.Name = "Story"
+	release.Issues = []JiraIssue{issue}
+
+	got, err := createConfluenceWikiMarkup(ConfluenceTemplate{Body: "./templates/confluence-body.wiki.tmpl"}, newReleaseData(release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{toc:maxLevel=1}\n\n{info}This release contains changes by 1 authors in 2 materials.{info}\n\nh1. Bug Fixes\n* BF1\n\nh1. Improvements\n* Impr1\n* [RPCStatus|https://example.com/rpc]\n\nh1. Issues\n* JI-2034 - Add host identifiers (Story)\n\n\n"
+	if want != got {
+		t.Fatalf("expected: %q, got: %q", want, got)
+	}
+}
+
+func TestGetConfluenceTemplate(t *testing.T) {
+	templates := []ConfluenceTemplate{
+		{Pipeline: "iotic-webbing", Title: "web"},
+		{Pipeline: "*", Title: "all"},
+	}
+	if got := getConfluenceTemplate(templates, "iotic-webbing").Title; got != "web" {
+		t.Errorf("unexpected template: %v", got)
+	}
+	if got := getConfluenceTemplate(templates, "other").Title; got != "all" {
+		t.Errorf("unexpected template: %v", got)
+	}
+	if got := getConfluenceTemplate(nil, "other").Title; got != "" {
+		t.Errorf("unexpected template: %v", got)
+	}
+}
@@ -61,7 +61,8 @@ func TestPublishToConfluence(t *testing.T) {
 		}
 	}
 
-	_, err := publishReleaseNotesToConfluence(cfg, date, "Test", "test", "v0.0.1", notes)
+	release := &Release{Title: "Test", Pipeline: "test", Version: "v0.0.1", Timestamp: date, Notes: notes}
+	_, _, err := publishReleaseNotesToConfluence(cfg, release)
 	if err != nil {
 		t.Error(err)
 	}
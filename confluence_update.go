@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfluenceVersion is the version of a blog post or page,
+// it has to be incremented on each update
+type ConfluenceVersion struct {
+	Number  int    `json:"number"`
+	Message string `json:"message,omitempty"`
+}
+
+// ConfluenceUpdate is the result of regenerating existing release notes
+type ConfluenceUpdate struct {
+	ID      string
+	Version int
+	// Updated is false when nothing changed and the update was skipped
+	Updated bool
+	Diff    []GroupDiff `json:",omitempty"`
+}
+
+// GroupDiff lists the bullet points added to and removed from a group of release notes
+type GroupDiff struct {
+	Name    string
+	Added   []string `json:",omitempty"`
+	Removed []string `json:",omitempty"`
+}
+
+// findConfluencePost finds the blog post (or page) with the same title,
+// blog posts are looked up on the day of the release
+func findConfluencePost(cfg *Config, post *ConfluencePost, day time.Time) (*ConfluenceBlogPost, error) {
+
+	// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content/#api-wiki-rest-api-content-get
+	query := url.Values{}
+	query.Set("spaceKey", post.Space.Key)
+	query.Set("type", post.Type)
+	query.Set("title", post.Title)
+	query.Set("expand", "body.editor2,version")
+	if post.Type == "blogpost" {
+		query.Set("postingDay", day.Format("2006/01/02"))
+	}
+	apiURL := fmt.Sprintf("%s/wiki/rest/api/content?%s", cfg.JiraUrl, query.Encode())
+
+	var list ConfluenceContentList
+	_, err := callConfluence(cfg, http.MethodGet, apiURL, nil, &list)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Results) == 0 {
+		return nil, nil
+	}
+	return &list.Results[0], nil
+}
+
+// getConfluenceContent returns the blog post (or page) with its body and version
+func getConfluenceContent(cfg *Config, id string) (*ConfluenceBlogPost, error) {
+
+	// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content/#api-wiki-rest-api-content-id-get
+	apiURL := fmt.Sprintf("%s/wiki/rest/api/content/%s?expand=body.editor2,version", cfg.JiraUrl, id)
+
+	var content ConfluenceBlogPost
+	_, err := callConfluence(cfg, http.MethodGet, apiURL, nil, &content)
+	if err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// updateConfluencePost replaces the body (or only the marked region of it)
+// of the existing post with a new version, unless the title and the normalized body are the same;
+// the diff of the bullet points of each group is only reported
+func updateConfluencePost(cfg *Config, existing *ConfluenceBlogPost, post *ConfluencePost) (*ConfluenceBlogPost, *ConfluenceUpdate, error) {
+	oldContent := ""
+	if existing.Body.Editor2 != nil {
+		oldContent = existing.Body.Editor2.Value
+	}
+	oldBody := oldContent
+	newContent := post.Body.Storage.Value
+	if cfg.ConfluenceMarkers {
+		content, oldRegion, newRegion, ok := spliceMarkedContent(oldContent, newContent)
+		if ok {
+			post.Body.Storage.Value = content
+			oldContent, newContent = oldRegion, newRegion
+		} else {
+			log.Printf("Replacing the whole body of %s - no markers found", existing.ID)
+		}
+	}
+	update := &ConfluenceUpdate{
+		ID:      existing.ID,
+		Version: existing.Version.Number,
+		Diff:    diffConfluenceContent(oldContent, newContent),
+	}
+	titleChanged := existing.Title != "" && existing.Title != post.Title
+	if !titleChanged && normalizeConfluenceContent(oldBody) == normalizeConfluenceContent(post.Body.Storage.Value) {
+		log.Printf("Skipping update of %s - no changes", existing.ID)
+		return existing, update, nil
+	}
+
+	// see https://developer.atlassian.com/cloud/confluence/rest/api-group-content/#api-wiki-rest-api-content-id-put
+	apiURL := fmt.Sprintf("%s/wiki/rest/api/content/%s", cfg.JiraUrl, existing.ID)
+
+	post.ID = existing.ID
+	post.Version = &ConfluenceVersion{
+		Number:  existing.Version.Number + 1,
+		Message: formatGroupDiffs(update.Diff),
+	}
+	var updated ConfluenceBlogPost
+	_, err := callConfluence(cfg, http.MethodPut, apiURL, post, &updated)
+	if err != nil {
+		return nil, update, fmt.Errorf("failed to update %s: %w", existing.ID, err)
+	}
+	update.Version = post.Version.Number
+	update.Updated = true
+	return &updated, update, nil
+}
+
+// diffConfluenceContent compares the bullet points of each group (heading) of two bodies
+func diffConfluenceContent(oldContent string, newContent string) []GroupDiff {
+	oldGroups, oldNames := extractContentGroups(oldContent)
+	newGroups, newNames := extractContentGroups(newContent)
+
+	names := newNames
+	for _, name := range oldNames {
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	diffs := []GroupDiff{}
+	for _, name := range names {
+		diff := GroupDiff{
+			Name:    name,
+			Added:   subtractStrings(newGroups[name], oldGroups[name]),
+			Removed: subtractStrings(oldGroups[name], newGroups[name]),
+		}
+		if len(diff.Added) > 0 || len(diff.Removed) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
+
+// volatileConfluenceAttributes are set by Confluence on each save, so they are not compared
+var volatileConfluenceAttributes = []string{"macro-id", "local-id", "schema-version"}
+
+// normalizeConfluenceContent returns the elements, the sorted attributes and the text of the (X)HTML body
+// with normalized spaces, so that two bodies can be compared
+func normalizeConfluenceContent(content string) string {
+	decoder := xml.NewDecoder(strings.NewReader("<body>" + content + "</body>"))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var buf strings.Builder
+	text := &strings.Builder{}
+	flush := func() {
+		if t := normalizeSpace(text.String()); t != "" {
+			buf.WriteString(t + "\n")
+		}
+		text.Reset()
+	}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			flush()
+			attrs := []string{}
+			for _, a := range t.Attr {
+				if !containsString(volatileConfluenceAttributes, a.Name.Local) {
+					attrs = append(attrs, fmt.Sprintf("%s:%s=%q", a.Name.Space, a.Name.Local, a.Value))
+				}
+			}
+			sort.Strings(attrs)
+			fmt.Fprintf(&buf, "<%s:%s %s>\n", t.Name.Space, t.Name.Local, strings.Join(attrs, " "))
+		case xml.EndElement:
+			flush()
+			fmt.Fprintf(&buf, "</%s:%s>\n", t.Name.Space, t.Name.Local)
+		case xml.CharData:
+			text.Write(t)
+		}
+	}
+	flush()
+	return buf.String()
+}
+
+// extractContentGroups reads the text of the list items under each heading of
+// the Confluence (X)HTML body, it returns the bullet points and the headings in order
+func extractContentGroups(content string) (map[string][]string, []string) {
+	groups := map[string][]string{}
+	names := []string{}
+	group := ""
+
+	// NOTE: the body is a fragment with HTML entities, so it's not strict XML
+	decoder := xml.NewDecoder(strings.NewReader("<body>" + content + "</body>"))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	heading := (*strings.Builder)(nil)
+	items := []*strings.Builder{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				heading = &strings.Builder{}
+			case "li":
+				items = append(items, &strings.Builder{})
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				if heading != nil {
+					group = normalizeSpace(heading.String())
+					if !containsString(names, group) {
+						names = append(names, group)
+					}
+					heading = nil
+				}
+			case "li":
+				if len(items) > 0 {
+					text := normalizeSpace(items[len(items)-1].String())
+					items = items[:len(items)-1]
+					if text != "" {
+						if !containsString(names, group) {
+							names = append(names, group)
+						}
+						groups[group] = append(groups[group], text)
+					}
+				}
+			}
+		case xml.CharData:
+			if heading != nil {
+				heading.Write(t)
+			} else if len(items) > 0 {
+				items[len(items)-1].Write(t)
+			}
+		}
+	}
+	return groups, names
+}
+
+func normalizeSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// subtractStrings returns the items of a which are not in b
+func subtractStrings(a []string, b []string) []string {
+	result := []string{}
+	for _, s := range a {
+		if !containsString(b, s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// formatGroupDiffs summarises the diff for the version message, e.g. "Improvements +2 -1, Bug Fixes +1"
+func formatGroupDiffs(diffs []GroupDiff) string {
+	parts := []string{}
+	for _, d := range diffs {
+		part := d.Name
+		if part == "" {
+			part = "(no heading)"
+		}
+		if len(d.Added) > 0 {
+			part += fmt.Sprintf(" +%d", len(d.Added))
+		}
+		if len(d.Removed) > 0 {
+			part += fmt.Sprintf(" -%d", len(d.Removed))
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		// NOTE: e.g. a link, a paragraph or the template changed
+		return "Release notes regenerated"
+	}
+	return "Release notes regenerated: " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+func TestDiffConfluenceContent(t *testing.T) {
+	type test struct {
+		old  string
+		new  string
+		want []GroupDiff
+	}
+	tests := []test{
+		{
+			old:  "<h1>Improvements</h1><ul><li>Impr1</li></ul>",
+			new:  "<h1>Improvements</h1>\n<ul>\n<li>Impr1 </li>\n</ul>",
+			want: []GroupDiff{},
+		},
+		{
+			old: "<h1>Improvements</h1><ul><li>Impr1</li></ul><h1>Bug Fixes</h1><ul><li>BF1</li></ul>",
+			new: "<h1>Improvements</h1><ul><li>Impr1</li><li><a href=\"https://example.com\">Impr2</a> &amp; more&nbsp;</li></ul>",
+			want: []GroupDiff{
+				{Name: "Improvements", Added: []string{"Impr2 & more"}, Removed: []string{}},
+				{Name: "Bug Fixes", Added: []string{}, Removed: []string{"BF1"}},
+			},
+		},
+		{
+			old: "<ul><li>no heading</li></ul>",
+			new: "<h1>Features</h1><ul><li>F1<ul><li>F1.1</li></ul></li></ul>",
+			want: []GroupDiff{
+				{Name: "Features", Added: []string{"F1.1", "F1"}, Removed: []string{}},
+				{Name: "", Added: []string{}, Removed: []string{"no heading"}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		got := diffConfluenceContent(tc.old, tc.new)
+		if !reflect.DeepEqual(tc.want, got) {
+			t.Fatalf("expected: %+v, got: %+v", tc.want, got)
+		}
+	}
+}
+
+func TestNormalizeConfluenceContent(t *testing.T) {
+	type test struct {
+		old  string
+		new  string
+		want bool
+	}
+	tests := []test{
+		{old: "<p>a  b</p>", new: "<p>\na b </p>", want: true},
+		{old: `<ac:structured-macro ac:name="toc" ac:macro-id="1" />`, new: `<ac:structured-macro ac:macro-id="2" ac:name="toc"/>`, want: true},
+		{old: `<a href="https://a.example.com">x</a>`, new: `<a href="https://b.example.com">x</a>`, want: false},
+		{old: "<p>Footer</p>", new: "", want: false},
+	}
+
+	for _, tc := range tests {
+		got := normalizeConfluenceContent(tc.old) == normalizeConfluenceContent(tc.new)
+		if tc.want != got {
+			t.Errorf("%s vs %s expected: %v, got: %v", tc.old, tc.new, tc.want, got)
+		}
+	}
+}
+
+func TestPublishUpdatesExistingPost(t *testing.T) {
+	type test struct {
+		existing    string
+		wantUpdated bool
+		wantMessage string
+	}
+	tests := []test{
+		{
+			existing:    "<h1>Improvements</h1><ul><li>Impr1</li></ul>",
+			wantUpdated: true,
+			wantMessage: "Release notes regenerated: Bug Fixes +1, Improvements +1",
+		},
+		{
+			existing:    "<h1>Bug Fixes</h1>\n<ul><li>BF1 </li></ul><h1>Improvements</h1><ul><li>Impr1</li><li>Impr2</li></ul>",
+			wantUpdated: false,
+		},
+		{
+			existing:    "<h1>Bug Fixes</h1><ul><li>BF1</li></ul><h1>Improvements</h1><ul><li>Impr1</li><li><a href=\"https://internal.example.com\">Impr2</a></li></ul>",
+			wantUpdated: true,
+			wantMessage: "Release notes regenerated",
+		},
+		{
+			existing:    "<p>Preamble</p><h1>Bug Fixes</h1><ul><li>BF1</li></ul><h1>Improvements</h1><ul><li>Impr1</li><li>Impr2</li></ul>",
+			wantUpdated: true,
+			wantMessage: "Release notes regenerated",
+		},
+	}
+
+	for _, tc := range tests {
+		updates := []ConfluencePost{}
+		cfg := NewDefaultConfig()
+		cfg.ConfluenceUpdateExisting = true
+		cfg.Client = &mocks.MockClient{
+			DoFunc: func(req *http.Request) (*http.Response, error) {
+				switch {
+				case req.URL.Path == "/wiki/rest/api/contentbody/convert/editor2":
+					return jsonResponse(http.StatusOK, &ConfluenceStorage{
+						Value:          "<h1>Bug Fixes</h1><ul><li>BF1</li></ul><h1>Improvements</h1><ul><li>Impr1</li><li>Impr2</li></ul>",
+						Representation: "editor2",
+					}), nil
+				case req.Method == http.MethodGet && req.URL.Path == "/wiki/rest/api/content":
+					if req.URL.Query().Get("postingDay") != "2021/03/10" {
+						t.Errorf("unexpected query: %v", req.URL.RawQuery)
+					}
+					existing := ConfluenceBlogPost{ID: "300", Version: ConfluenceVersion{Number: 3}}
+					existing.Body.Editor2 = &ConfluenceStorage{Value: tc.existing, Representation: "editor2"}
+					return jsonResponse(http.StatusOK, &ConfluenceContentList{Results: []ConfluenceBlogPost{existing}}), nil
+				case req.Method == http.MethodPut && req.URL.Path == "/wiki/rest/api/content/300":
+					var post ConfluencePost
+					body, _ := ioutil.ReadAll(req.Body)
+					json.Unmarshal(body, &post)
+					updates = append(updates, post)
+					return jsonResponse(http.StatusOK, &ConfluenceBlogPost{ID: "300", Version: *post.Version}), nil
+				}
+				t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+				return jsonResponse(http.StatusOK, map[string]string{}), nil
+			},
+		}
+
+		post, update, err := publishReleaseNotesToConfluence(cfg, newTestRelease())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if post.ID != "300" || update.Updated != tc.wantUpdated {
+			t.Fatalf("unexpected update: %+v", update)
+		}
+		if !tc.wantUpdated {
+			if len(updates) != 0 || update.Version != 3 {
+				t.Errorf("expected no update, got: %+v", updates)
+			}
+			continue
+		}
+		if len(updates) != 1 || updates[0].ID != "300" || updates[0].Version.Number != 4 || update.Version != 4 {
+			t.Fatalf("unexpected updates: %+v", updates)
+		}
+		if updates[0].Version.Message != tc.wantMessage {
+			t.Errorf("unexpected version message: %v", updates[0].Version.Message)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// EmailConfig sends the release notes by email (SMTP)
+type EmailConfig struct {
+	Host     string
+	Port     int
+	From     string
+	Username string
+	// Password is a secret, see `smtppassword`
+	Password string
+	StartTLS bool
+	// EmlDir is a directory to write each email to as a .eml file for review;
+	// if the Host is not set, the emails are only written to the directory
+	EmlDir string
+	Routes []EmailRoute
+}
+
+// EmailRoute sends the release notes of a pipeline ("*" for all pipelines) to the recipients
+type EmailRoute struct {
+	Pipeline string
+	To       []string
+}
+
+// EmailPublisher sends the release notes as multipart HTML and plain text email
+type EmailPublisher struct{}
+
+func (p *EmailPublisher) Name() string {
+	return "email"
+}
+
+func (p *EmailPublisher) Publish(cfg *Config, release *Release) error {
+	recipients := getEmailRecipients(cfg.Email, release.Pipeline)
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	message, err := createEmail(cfg.Email.From, recipients, newReleaseData(release))
+	if err != nil {
+		return err
+	}
+
+	if cfg.Email.EmlDir != "" {
+		filename := filepath.Join(cfg.Email.EmlDir, fmt.Sprintf("%s-%s.eml", release.Pipeline, release.Version))
+		err = os.WriteFile(filename, message, 0644)
+		if err != nil {
+			return err
+		}
+		log.Printf("Written %s", filename)
+	}
+
+	if cfg.Email.Host == "" {
+		return nil
+	}
+	return sendEmail(cfg.Email, recipients, message)
+}
+
+func getEmailRecipients(email EmailConfig, pipeline string) []string {
+	recipients := []string{}
+	for _, route := range email.Routes {
+		if matchPipeline(route.Pipeline, pipeline) {
+			recipients = append(recipients, route.To...)
+		}
+	}
+	return unique(recipients)
+}
+
+var emailHTMLTemplate = template.Must(template.New("email").Funcs(htmlTemplateFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{ .Title }} Release Notes {{ .Version }}</title></head>
+<body>
+<h1>{{ .Title }} Release Notes {{ .Version }}</h1>
+<p>{{ .Timestamp.Format "2006-01-02" }}</p>
+{{- range .Groups }}
+<h2>{{ .Name }}</h2>
+{{ jiraHTML .BulletPoints }}
+{{- end }}
+{{- if .URL }}
+<p><a href="{{ .URL }}">Read the release notes in Confluence</a></p>
+{{- end }}
+</body>
+</html>
+`))
+
+func createEmailText(data *ReleaseData) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%s Release Notes %s\n%s\n", data.Title, data.Version, data.Timestamp.Format("2006-01-02"))
+	for _, group := range data.Groups {
+		fmt.Fprintf(&buf, "\n%s\n\n", group.Name)
+		for _, l := range group.BulletPoints {
+			buf.WriteString(jiraToText(l) + "\n")
+		}
+	}
+	if data.URL != "" {
+		fmt.Fprintf(&buf, "\nRead the release notes in Confluence: %s\n", data.URL)
+	}
+	return buf.String()
+}
+
+// createEmail creates a multipart/alternative email message,
+// see https://datatracker.ietf.org/doc/html/rfc2046#section-5.1.4
+func createEmail(from string, to []string, data *ReleaseData) ([]byte, error) {
+	var htmlBody bytes.Buffer
+	err := emailHTMLTemplate.Execute(&htmlBody, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", createEmailText(data)},
+		{"text/html; charset=utf-8", htmlBody.String()},
+	}
+	for _, p := range parts {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = writeQuotedPrintable(part, p.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	subject := fmt.Sprintf("%s Release Notes %s", data.Title, data.Version)
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "Message-ID: <%s@%s>\r\n", xid.New(), emailDomain(from))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	_, err := qp.Write([]byte(content))
+	if err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func emailDomain(address string) string {
+	addr := emailAddress(address)
+	return addr[strings.LastIndex(addr, "@")+1:]
+}
+
+func sendEmail(email EmailConfig, to []string, message []byte) error {
+	port := email.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(email.Host, fmt.Sprintf("%d", port))
+
+	log.Printf("Calling SMTP %s", addr)
+
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if email.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+		}
+		err = c.StartTLS(&tls.Config{ServerName: email.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if email.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", email.Username, email.Password, email.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.Mail(emailAddress(email.From))
+	if err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		err = c.Rcpt(emailAddress(recipient))
+		if err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(message)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// emailAddress returns just the address part of e.g. "Release Notes <rn@example.com>"
+func emailAddress(address string) string {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return strings.TrimSpace(address)
+	}
+	return parsed.Address
+}
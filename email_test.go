@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts a single email, just enough of RFC 5321 for net/smtp
+type fakeSMTPServer struct {
+	listener   net.Listener
+	auth       string
+	from       string
+	recipients []string
+	data       []byte
+	done       chan struct{}
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{listener: listener, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	write := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	write("220 localhost ESMTP fake")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO":
+			write("250-localhost")
+			write("250 AUTH PLAIN")
+		case "AUTH":
+			s.auth = line
+			write("235 2.7.0 Authentication successful")
+		case "MAIL":
+			s.from = line
+			write("250 OK")
+		case "RCPT":
+			s.recipients = append(s.recipients, line)
+			write("250 OK")
+		case "DATA":
+			write("354 End data with <CR><LF>.<CR><LF>")
+			var data bytes.Buffer
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.data = data.Bytes()
+			write("250 OK")
+		case "QUIT":
+			write("221 Bye")
+			return
+		default:
+			write("502 Command not implemented")
+		}
+	}
+}
+
+func TestEmailPublisher(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	defer server.listener.Close()
+
+	emlDir := t.TempDir()
+	cfg := NewDefaultConfig()
+	cfg.Email = EmailConfig{
+		Host:     "localhost",
+		Port:     server.port(),
+		From:     "Release Notes <release-notes@example.com>",
+		Username: "release-notes@example.com",
+		Password: "secret",
+		EmlDir:   emlDir,
+		Routes: []EmailRoute{
+			{Pipeline: "iotic-webbing", To: []string{"customers@example.com"}},
+			{Pipeline: "*", To: []string{"Support <support@example.com>"}},
+			{Pipeline: "another-pipeline", To: []string{"another@example.com"}},
+		},
+	}
+
+	err := (&EmailPublisher{}).Publish(cfg, newTestRelease())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-server.done
+
+	if server.auth == "" {
+		t.Errorf("expected the client to authenticate")
+	}
+	if server.from != "MAIL FROM:<release-notes@example.com>" {
+		t.Errorf("unexpected sender: %v", server.from)
+	}
+	if strings.Join(server.recipients, ",") != "RCPT TO:<customers@example.com>,RCPT TO:<support@example.com>" {
+		t.Errorf("unexpected recipients: %v", server.recipients)
+	}
+
+	eml, err := ioutil.ReadFile(filepath.Join(emlDir, "iotic-webbing-2.0.390.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(eml, server.data) {
+		t.Errorf("the .eml file doesn't match the sent email")
+	}
+
+	parts := readEmailParts(t, server.data)
+	if !strings.Contains(parts["text/plain"], "- RPCStatus (https://example.com/rpc)") {
+		t.Errorf("unexpected plain text:\n%s", parts["text/plain"])
+	}
+	if !strings.Contains(parts["text/html"], `<li><a href="https://example.com/rpc">RPCStatus</a></li>`) {
+		t.Errorf("unexpected html:\n%s", parts["text/html"])
+	}
+}
+
+func TestEmailPublisherOnlyWritesEml(t *testing.T) {
+	emlDir := t.TempDir()
+	cfg := NewDefaultConfig()
+	cfg.Email = EmailConfig{
+		From:   "release-notes@example.com",
+		EmlDir: emlDir,
+		Routes: []EmailRoute{{Pipeline: "*", To: []string{"customers@example.com"}}},
+	}
+
+	err := (&EmailPublisher{}).Publish(cfg, newTestRelease())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(filepath.Join(emlDir, "iotic-webbing-2.0.390.eml"))
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func readEmailParts(t *testing.T, data []byte) map[string]string {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "The Best Web Release Notes 2.0.390" {
+		t.Errorf("unexpected subject: %v", subject)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type: %v %v", mediaType, err)
+	}
+
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		// NOTE: NextPart decodes the quoted-printable transfer encoding
+		content, _ := ioutil.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(content)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got: %d", len(parts))
+	}
+	return parts
+}
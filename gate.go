@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// GateConfig checks the quality of the release notes before they are published
+type GateConfig struct {
+	// Labels and IssueTypes mark the Jira issues with breaking changes, e.g. [breaking]
+	Labels     []string
+	IssueTypes []string
+	// Group is the group the breaking changes have to be in, "Breaking Change(s)" by default
+	Group string
+	// ProtectedMaterials are the git URLs or material names (globs) where every commit needs a Jira key
+	ProtectedMaterials []string
+	// Enforce runs the gate before publishing; a failed gate stops the release,
+	// unless it is approved with `approved=true` in the query string
+	Enforce bool
+}
+
+// the checks of the gate
+const (
+	gateBreakingChange = "breaking-change"
+	gateUnparsedNotes  = "unparsed-notes"
+	gateMissingJiraKey = "missing-jira-key"
+)
+
+// GateReport is the result of the gate, the GoCD stage fails on 422
+type GateReport struct {
+	Passed   bool
+	Approved bool `json:",omitempty"`
+	Failures []GateFailure
+}
+
+type GateFailure struct {
+	Check    string
+	Key      string `json:",omitempty"`
+	Material string `json:",omitempty"`
+	Revision string `json:",omitempty"`
+	Reason   string
+}
+
+// validateRelease runs all the checks of the gate
+func validateRelease(cfg *Config, comparison *GocdPipelineComparison, jiraIssues []JiraIssue) *GateReport {
+	report := &GateReport{Failures: []GateFailure{}}
+	for _, issue := range jiraIssues {
+		jiraNotes := getJiraTextField(issue, cfg.JiraReleaseNotesField)
+		groups := []Group{}
+		if jiraNotes != "" {
+			groups = extractGroups(jiraNotes)
+			if !hasHeadings(jiraNotes) {
+				report.Failures = append(report.Failures, GateFailure{
+					Check:  gateUnparsedNotes,
+					Key:    issue.Key,
+					Reason: "the release notes have no headings, e.g. h4. Improvements",
+				})
+			}
+		}
+		if isBreakingChangeIssue(cfg.Gate, issue) && !hasBreakingChangeGroup(cfg.Gate, groups) {
+			report.Failures = append(report.Failures, GateFailure{
+				Check:  gateBreakingChange,
+				Key:    issue.Key,
+				Reason: "the issue is a breaking change, but its release notes have no breaking changes group",
+			})
+		}
+	}
+	report.Failures = append(report.Failures, findCommitsWithoutJiraKeys(cfg.Gate, comparison)...)
+	report.Passed = len(report.Failures) == 0
+	return report
+}
+
+func hasHeadings(jiraNotes string) bool {
+	for _, line := range strings.Split(jiraNotes, "\n") {
+		if findHeader(line) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func isBreakingChangeIssue(gate GateConfig, issue JiraIssue) bool {
+	for _, label := range issue.Fields.Labels {
+		if containsString(gate.Labels, label) {
+			return true
+		}
+	}
+	return containsString(gate.IssueTypes, issue.Fields.Issuetype.Name)
+}
+
+func hasBreakingChangeGroup(gate GateConfig, groups []Group) bool {
+	for _, g := range groups {
+		if gate.Group != "" && strings.EqualFold(g.Name, gate.Group) {
+			return true
+		}
+		if gate.Group == "" && isBreakingChangeGroup(g.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func findCommitsWithoutJiraKeys(gate GateConfig, comparison *GocdPipelineComparison) []GateFailure {
+	failures := []GateFailure{}
+	if comparison == nil {
+		return failures
+	}
+	for _, change := range comparison.Changes {
+		attributes := change.Material.Attributes
+		if change.Material.Type == "dependency" || !isProtectedMaterial(gate, attributes.URL, attributes.Name) {
+			continue
+		}
+		for _, r := range change.Revision {
+			if len(findJiraIssueKeys(r.CommitMessage)) == 0 {
+				failures = append(failures, GateFailure{
+					Check:    gateMissingJiraKey,
+					Material: attributes.URL,
+					Revision: r.RevisionSha,
+					Reason:   fmt.Sprintf("the commit has no Jira key: %s", strings.SplitN(r.CommitMessage, "\n", 2)[0]),
+				})
+			}
+		}
+	}
+	return failures
+}
+
+func isProtectedMaterial(gate GateConfig, url string, name string) bool {
+	for _, pattern := range gate.ProtectedMaterials {
+		for _, value := range []string{url, name} {
+			if value == "" {
+				continue
+			}
+			if ok, _ := path.Match(pattern, value); ok || pattern == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkReleaseGate returns an error with the report, when the gate fails and is not approved
+func checkReleaseGate(report *GateReport, approved bool) error {
+	if report.Passed {
+		return nil
+	}
+	if approved {
+		report.Approved = true
+		return nil
+	}
+	return &StatusError{
+		StatusCode: http.StatusUnprocessableEntity,
+		Err:        fmt.Errorf("the release gate failed with %d failures", len(report.Failures)),
+		Body:       report,
+	}
+}
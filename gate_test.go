@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+func newGateTestIssue(key string, issueType string, labels []string, notes string) JiraIssue {
+	issue := JiraIssue{Key: key}
+	issue.Fields.Issuetype.Name = issueType
+	issue.Fields.Labels = labels
+	issue.Fields.Customfield10110 = notes
+	return issue
+}
+
+func TestValidateRelease(t *testing.T) {
+	type test struct {
+		gate  GateConfig
+		issue JiraIssue
+		want  []string
+	}
+	tests := []test{
+		{
+			gate:  GateConfig{Labels: []string{"breaking"}},
+			issue: newGateTestIssue("JI-1", "Story", []string{"breaking"}, "h4. Improvements\n* Impr1"),
+			want:  []string{"breaking-change JI-1"},
+		},
+		{
+			gate:  GateConfig{Labels: []string{"breaking"}},
+			issue: newGateTestIssue("JI-2", "Story", []string{"breaking"}, "h4. Breaking Changes\n* BC1"),
+			want:  []string{},
+		},
+		{
+			gate:  GateConfig{IssueTypes: []string{"API Change"}, Group: "Incompatible"},
+			issue: newGateTestIssue("JI-3", "API Change", nil, "h4. Breaking Change\n* BC1"),
+			want:  []string{"breaking-change JI-3"},
+		},
+		{
+			gate:  GateConfig{IssueTypes: []string{"API Change"}},
+			issue: newGateTestIssue("JI-4", "API Change", nil, "* no heading"),
+			want:  []string{"unparsed-notes JI-4", "breaking-change JI-4"},
+		},
+		{
+			gate:  GateConfig{Labels: []string{"breaking"}},
+			issue: newGateTestIssue("JI-5", "Bug", []string{"bug"}, ""),
+			want:  []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		cfg := &Config{JiraReleaseNotesField: defaultJiraReleaseNotesField, Gate: tc.gate}
+		report := validateRelease(cfg, nil, []JiraIssue{tc.issue})
+		got := []string{}
+		for _, f := range report.Failures {
+			got = append(got, f.Check+" "+f.Key)
+		}
+		if !reflect.DeepEqual(tc.want, got) || report.Passed != (len(tc.want) == 0) {
+			t.Fatalf("expected: %v, got: %v %v", tc.want, report.Passed, got)
+		}
+	}
+}
+
+func TestCheckReleaseGate(t *testing.T) {
+	failed := &GateReport{Failures: []GateFailure{{Check: gateUnparsedNotes}}}
+	err := checkReleaseGate(failed, false)
+	statusErr, ok := err.(*StatusError)
+	if !ok || statusErr.StatusCode != http.StatusUnprocessableEntity || statusErr.Body != failed {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = checkReleaseGate(failed, true)
+	if err != nil || !failed.Approved {
+		t.Errorf("approved gate should pass: %v %+v", err, failed)
+	}
+}
+
+func newSampleMockClient(t *testing.T, cfg *Config) HTTPClient {
+	return &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			var json []byte
+			if strings.HasSuffix(cfg.GocdUrl, req.Host) {
+				json = readSampleGocdPipeline(t)
+			} else if strings.HasSuffix(cfg.JiraUrl, req.Host) {
+				json = readSampleJira(t, req.URL.Path)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(json)),
+			}, nil
+		},
+	}
+}
+
+func TestHandleValidateRequest(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Client = newSampleMockClient(t, cfg)
+	cfg.Gate = GateConfig{
+		Labels:             []string{"breaking_change"},
+		ProtectedMaterials: []string{"git@github.com:Iotic-Labs/docker-*"},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/validate?pipeline=iotic-webbing&counter=614", nil)
+	rr := httptest.NewRecorder()
+	handleValidateRequest(rr, req, cfg)
+
+	if status := rr.Code; status != http.StatusUnprocessableEntity {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusUnprocessableEntity)
+	}
+	var report GateReport
+	err := json.Unmarshal(rr.Body.Bytes(), &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, f := range report.Failures {
+		got = append(got, f.Check+" "+f.Key+f.Revision)
+	}
+	want := []string{
+		"unparsed-notes JI-1227",
+		"missing-jira-key f78a137ba7eef7fdc9bfcc50ee8c6289ab27ee39",
+		"missing-jira-key 8bbbcf7380f5261fdb29d2db763ae3212c453972",
+		"missing-jira-key 59895c9c0ce0bd4e2a86b690033ea8b4d7e2d6b1",
+		"missing-jira-key 0965698f3a962c455f4437311888d51ed0ddf389",
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected: %v, got: %v", want, got)
+	}
+}
+
+func TestHandlerGateBlocksRelease(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Client = newSampleMockClient(t, cfg)
+	cfg.Gate = GateConfig{ProtectedMaterials: []string{"git@github.com:Iotic-Labs/docker-certmgr"}, Enforce: true}
+
+	req, _ := http.NewRequest(http.MethodGet, "/?title=The+Best+Web&pipeline=iotic-webbing&counter=614", nil)
+	rr := httptest.NewRecorder()
+	handleRequest(rr, req, cfg)
+
+	if status := rr.Code; status != http.StatusUnprocessableEntity {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusUnprocessableEntity)
+	}
+	if !strings.Contains(rr.Body.String(), "59895c9c0ce0bd4e2a86b690033ea8b4d7e2d6b1") {
+		t.Errorf("unexpected body: %v", rr.Body.String())
+	}
+}
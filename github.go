@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const defaultGithubAPIURL = "https://api.github.com"
+
+// GithubConfig creates or updates GitHub Releases in the repositories
+// the changes came from; GitHub Enterprise and Gitea have a compatible API,
+// e.g. https://github.your-company.com/api/v3 or https://gitea.your-company.com/api/v1
+type GithubConfig struct {
+	APIURL string
+	// Token is a secret, see `githubtoken`
+	Token  string
+	Routes []GithubRoute
+}
+
+// GithubRoute configures the releases of a pipeline ("*" for all pipelines)
+type GithubRoute struct {
+	Pipeline string
+	// TagTemplate is a text/template of the tag name, e.g. "v{{.Version}}"
+	TagTemplate string
+	Draft       bool
+	Prerelease  bool
+	// Repos limits the releases to these "owner/repo",
+	// by default a release is created in every repository with changes
+	Repos []string
+}
+
+// GithubRelease represents a GitHub (or Gitea) release
+type GithubRelease struct {
+	ID              int64  `json:"id,omitempty"`
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+	Name            string `json:"name"`
+	Body            string `json:"body"`
+	Draft           bool   `json:"draft"`
+	Prerelease      bool   `json:"prerelease"`
+}
+
+// GitRepo is a repository of a git material
+type GitRepo struct {
+	Host     string
+	FullName string
+	// Revision is the latest revision of the repository in the release
+	Revision string
+}
+
+// GithubPublisher creates or updates a release in each repository
+type GithubPublisher struct{}
+
+func (p *GithubPublisher) Name() string {
+	return "github"
+}
+
+func (p *GithubPublisher) Publish(cfg *Config, release *Release) error {
+	apiURL := cfg.Github.APIURL
+	if apiURL == "" {
+		apiURL = defaultGithubAPIURL
+	}
+	host := getGithubHost(apiURL)
+
+	for _, route := range cfg.Github.Routes {
+		if !matchPipeline(route.Pipeline, release.Pipeline) {
+			continue
+		}
+		tag, err := renderTagName(route.TagTemplate, release)
+		if err != nil {
+			return err
+		}
+		githubRelease := &GithubRelease{
+			TagName:    tag,
+			Name:       fmt.Sprintf("%s %s", release.Title, release.Version),
+			Body:       createMarkdownReleaseNotes(release),
+			Draft:      route.Draft,
+			Prerelease: route.Prerelease,
+		}
+		for _, repo := range getGitRepos(release.Comparison) {
+			if repo.Host != host || (len(route.Repos) > 0 && !containsString(route.Repos, repo.FullName)) {
+				continue
+			}
+			githubRelease.TargetCommitish = repo.Revision
+			err = createOrUpdateGithubRelease(cfg, apiURL, repo.FullName, githubRelease)
+			if err != nil {
+				return fmt.Errorf("%s: %w", repo.FullName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// getGithubHost returns the host of the repository URLs for the API URL
+func getGithubHost(apiURL string) string {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return ""
+	}
+	if u.Host == "api.github.com" {
+		return "github.com"
+	}
+	return u.Hostname()
+}
+
+func renderTagName(tagTemplate string, release *Release) (string, error) {
+	if tagTemplate == "" {
+		tagTemplate = "{{.Version}}"
+	}
+	return renderTextTemplate("tag", tagTemplate, newReleaseData(release))
+}
+
+func createMarkdownReleaseNotes(release *Release) string {
+	var buf strings.Builder
+	for _, group := range sortedGroups(release.Notes) {
+		fmt.Fprintf(&buf, "## %s\n\n", group.Name)
+		for _, l := range group.BulletPoints {
+			buf.WriteString(jiraToMarkdown(l) + "\n")
+		}
+		buf.WriteString("\n")
+	}
+	if release.Post != nil && release.Post.URL() != "" {
+		fmt.Fprintf(&buf, "See the [release notes](%s) for more details.\n", release.Post.URL())
+	}
+	return buf.String()
+}
+
+// NOTE: e.g. git@github.com:Iotic-Labs/system-core.git
+// or https://github.com/Iotic-Labs/system-core.git
+// or ssh://git@github.com:22/Iotic-Labs/system-core
+var gitRepoURL = regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?([^:/]+)(?::\d+)?[:/]([^/]+/[^/]+?)(?:\.git)?/?$`)
+
+func parseGitRepoURL(repoURL string) (host string, fullName string, ok bool) {
+	match := gitRepoURL.FindStringSubmatch(repoURL)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
+
+// getGitRepos returns each repository of the git materials once
+func getGitRepos(comparison *GocdPipelineComparison) []GitRepo {
+	repos := []GitRepo{}
+	if comparison == nil {
+		return repos
+	}
+	seen := map[string]bool{}
+	for _, change := range comparison.Changes {
+		if change.Material.Type != "git" || len(change.Revision) == 0 {
+			continue
+		}
+		host, fullName, ok := parseGitRepoURL(change.Material.Attributes.URL)
+		// NOTE: several materials can point at the same repository, e.g. with different branches
+		if !ok || seen[host+"/"+fullName] {
+			continue
+		}
+		seen[host+"/"+fullName] = true
+		repos = append(repos, GitRepo{
+			Host:     host,
+			FullName: fullName,
+			Revision: change.Revision[0].RevisionSha,
+		})
+	}
+	return repos
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// maxGithubReleasePages limits the paging through the releases when looking for the tag
+var maxGithubReleasePages = 10
+
+func createOrUpdateGithubRelease(cfg *Config, apiURL string, repo string, release *GithubRelease) error {
+	existing, err := findGithubRelease(cfg, apiURL, repo, release.TagName)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		// see https://docs.github.com/en/rest/releases/releases#create-a-release
+		postURL := fmt.Sprintf("%s/repos/%s/releases", apiURL, repo)
+		_, err = callGithub(cfg, http.MethodPost, postURL, release, nil)
+		return err
+	}
+
+	// see https://docs.github.com/en/rest/releases/releases#update-a-release
+	patchURL := fmt.Sprintf("%s/repos/%s/releases/%d", apiURL, repo, existing.ID)
+	update := *release
+	// NOTE: the tag already exists, so don't move it
+	update.TargetCommitish = ""
+	_, err = callGithub(cfg, http.MethodPatch, patchURL, &update, nil)
+	return err
+}
+
+// findGithubRelease returns the release of the tag, or nil;
+// NOTE: draft releases cannot be found by the tag name, so the releases are listed instead
+func findGithubRelease(cfg *Config, apiURL string, repo string, tagName string) (*GithubRelease, error) {
+	for page := 1; page <= maxGithubReleasePages; page++ {
+		// see https://docs.github.com/en/rest/releases/releases#list-releases
+		listURL := fmt.Sprintf("%s/repos/%s/releases?per_page=100&page=%d", apiURL, repo, page)
+		releases := []GithubRelease{}
+		_, err := callGithub(cfg, http.MethodGet, listURL, nil, &releases)
+		if err != nil {
+			return nil, err
+		}
+		for i := range releases {
+			if releases[i].TagName == tagName {
+				return &releases[i], nil
+			}
+		}
+		if len(releases) == 0 {
+			break
+		}
+	}
+	return nil, nil
+}
+
+func callGithub(cfg *Config, method string, apiURL string, payload interface{}, result interface{}) (int, error) {
+
+	log.Printf("Calling %s %s", method, apiURL)
+
+	body := &bytes.Buffer{}
+	if payload != nil {
+		jsonStr, _ := json.Marshal(payload)
+		body = bytes.NewBuffer(jsonStr)
+	}
+	req, err := http.NewRequest(method, apiURL, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("token %s", cfg.Github.Token))
+	req.Header.Add("Accept", "application/vnd.github+json")
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	response, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%d %s", resp.StatusCode, string(response))
+	}
+	if result != nil {
+		err = json.Unmarshal(response, result)
+	}
+	return resp.StatusCode, err
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGitRepoURL(t *testing.T) {
+	type test struct {
+		input    string
+		host     string
+		fullName string
+		ok       bool
+	}
+	tests := []test{
+		{input: "git@github.com:Iotic-Labs/system-core.git", host: "github.com", fullName: "Iotic-Labs/system-core", ok: true},
+		{input: "git@github.com:Iotic-Labs/iotic-service-proto", host: "github.com", fullName: "Iotic-Labs/iotic-service-proto", ok: true},
+		{input: "https://github.com/Iotic-Labs/system-core.git", host: "github.com", fullName: "Iotic-Labs/system-core", ok: true},
+		{input: "ssh://git@gitea.example.com:2222/team/repo.git", host: "gitea.example.com", fullName: "team/repo", ok: true},
+		{input: "not a url", ok: false},
+	}
+
+	for _, tc := range tests {
+		host, fullName, ok := parseGitRepoURL(tc.input)
+		if host != tc.host || fullName != tc.fullName || ok != tc.ok {
+			t.Fatalf("%s: expected: %v %v %v, got: %v %v %v", tc.input, tc.host, tc.fullName, tc.ok, host, fullName, ok)
+		}
+	}
+}
+
+func TestGithubPublisherCreatesThenUpdatesDraftRelease(t *testing.T) {
+	requests := []string{}
+	releases := map[string]GithubRelease{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		if r.Header.Get("Authorization") != "token t0k3n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var release GithubRelease
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &release)
+		switch r.Method {
+		case http.MethodGet:
+			list := []GithubRelease{}
+			if r.URL.Query().Get("page") == "1" {
+				for _, existing := range releases {
+					list = append(list, existing)
+				}
+			}
+			json, _ := json.Marshal(list)
+			w.Write(json)
+		case http.MethodPost:
+			release.ID = int64(len(releases) + 1)
+			releases[release.TagName] = release
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodPatch:
+			releases[release.TagName] = release
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	cfg := NewDefaultConfig()
+	cfg.Client = server.Client()
+	cfg.Github = GithubConfig{
+		APIURL: server.URL + "/api/v3",
+		Token:  "t0k3n",
+		Routes: []GithubRoute{{Pipeline: "iotic-webbing", TagTemplate: "v{{.Version}}", Draft: true, Prerelease: true}},
+	}
+
+	release := newTestRelease()
+	release.Comparison = readSampleComparison(t, "./sample-data/gocd-pipeline-compare-short.json")
+	release.Comparison.Changes[0].Material.Attributes.URL = fmt.Sprintf("git@%s:Iotic-Labs/system-core.git", serverURL.Hostname())
+	// NOTE: a second material of the same repository, e.g. another branch, is published once
+	release.Comparison.Changes = append(release.Comparison.Changes, release.Comparison.Changes[0])
+	release.Comparison.Changes[len(release.Comparison.Changes)-1].Material.Attributes.URL = fmt.Sprintf("https://%s/Iotic-Labs/system-core", serverURL.Hostname())
+
+	for i := 0; i < 2; i++ {
+		err := (&GithubPublisher{}).Publish(cfg, release)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		"GET /api/v3/repos/Iotic-Labs/system-core/releases?per_page=100&page=1",
+		"POST /api/v3/repos/Iotic-Labs/system-core/releases",
+		"GET /api/v3/repos/Iotic-Labs/system-core/releases?per_page=100&page=1",
+		"PATCH /api/v3/repos/Iotic-Labs/system-core/releases/1",
+	}
+	if !reflect.DeepEqual(want, requests) {
+		t.Errorf("expected: %v, got: %v", want, requests)
+	}
+	created := releases["v2.0.390"]
+	if len(releases) != 1 || !created.Draft || !created.Prerelease || created.Name != "The Best Web 2.0.390" {
+		t.Errorf("unexpected release: %+v", created)
+	}
+	if !strings.Contains(created.Body, "## Improvements\n\n- Impr1\n- [RPCStatus](https://example.com/rpc)\n") {
+		t.Errorf("unexpected release body: %v", created.Body)
+	}
+}
+
+func readSampleComparison(t *testing.T, filename string) *GocdPipelineComparison {
+	validJSON, err := os.ReadFile(filename)
+	if err != nil {
+		t.Errorf("could not read file: %s", err)
+	}
+	comparison, err := parseGocdPipelineComparison(validJSON)
+	if err != nil {
+		t.Error(err)
+	}
+	return &comparison
+}
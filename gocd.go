@@ -114,7 +114,6 @@ type GocdPipelineComparison struct {
 }
 
 func validateHistory(json GocdPipelineHistory) error {
-	validate = validator.New()
 	err := validate.Struct(json)
 	if err != nil {
 
@@ -133,7 +132,6 @@ func validateHistory(json GocdPipelineHistory) error {
 }
 
 func validateComparison(json GocdPipelineComparison) error {
-	validate = validator.New()
 	err := validate.Struct(json)
 	if err != nil {
 
@@ -203,6 +201,124 @@ func getGocdPipelineHistory(cfg *Config, pipeline string, counter int) (*GocdPip
 	return loadPipelineHistoryFromResponse(resp.Body)
 }
 
+// GocdPipelineHistoryPage is a page of the runs of a pipeline, the newest first
+type GocdPipelineHistoryPage struct {
+	Links struct {
+		Next struct {
+			Href string `json:"href"`
+		} `json:"next"`
+	} `json:"_links"`
+	Pipelines []GocdPipelineHistory `json:"pipelines"`
+}
+
+// getGocdPipelineHistoryPage returns the latest runs of the pipeline, or nil when they haven't changed since the etag
+func getGocdPipelineHistoryPage(cfg *Config, pipeline string, etag string) (*GocdPipelineHistoryPage, string, error) {
+
+	// see https://api.gocd.org/current/#get-pipeline-history
+	apiURL := fmt.Sprintf("%s/go/api/pipelines/%s/history", cfg.GocdUrl, pipeline)
+	return getGocdPipelineHistoryPageURL(cfg, apiURL, etag)
+}
+
+// maxHistoryPages limits the paging through the history of a pipeline
+var maxHistoryPages = 100
+
+// getGocdPipelineHistoryPageURL returns the page of the URL, e.g. the next link of the previous page
+func getGocdPipelineHistoryPageURL(cfg *Config, apiURL string, etag string) (*GocdPipelineHistoryPage, string, error) {
+	log.Printf("Calling %s", apiURL)
+
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", cfg.GocdApiKey))
+	req.Header.Add("Accept", "application/vnd.go.cd.v1+json")
+	if etag != "" {
+		req.Header.Add("If-None-Match", etag)
+	}
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, etag, nil
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, "", fmt.Errorf("401 Unauthorized")
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to get the pipeline history: %d %s", resp.StatusCode, string(body))
+	}
+
+	var page GocdPipelineHistoryPage
+	err = json.Unmarshal(body, &page)
+	if err != nil {
+		return nil, "", err
+	}
+	return &page, resp.Header.Get("ETag"), nil
+}
+
+// GocdStageInstance is a run of a stage with the state transitions of its jobs
+type GocdStageInstance struct {
+	Name    string `json:"name"`
+	Counter string `json:"counter"`
+	Result  string `json:"result"`
+	Jobs    []struct {
+		Name                string `json:"name"`
+		State               string `json:"state"`
+		Result              string `json:"result"`
+		JobStateTransitions []struct {
+			State           string `json:"state"`
+			StateChangeTime int64  `json:"state_change_time"`
+		} `json:"job_state_transitions"`
+	} `json:"jobs"`
+}
+
+func getGocdStageInstance(cfg *Config, pipeline string, counter int, stage string, stageCounter string) (*GocdStageInstance, error) {
+
+	// see https://api.gocd.org/current/#get-stage-instance
+	apiURL := fmt.Sprintf("%s/go/api/stages/%s/%d/%s/%s", cfg.GocdUrl, pipeline, counter, stage, stageCounter)
+
+	log.Printf("Calling %s", apiURL)
+
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", cfg.GocdApiKey))
+	req.Header.Add("Accept", "application/vnd.go.cd.v3+json")
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("401 Unauthorized")
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get the stage %s of %s/%d: %d %s", stage, pipeline, counter, resp.StatusCode, string(body))
+	}
+
+	var instance GocdStageInstance
+	err = json.Unmarshal(body, &instance)
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
 func getGocdPipelineComparison(cfg *Config, pipeline string, counter int) (*GocdPipelineComparison, error) {
 
 	prevCounter := counter - 1
@@ -15,10 +15,10 @@ import (
 )
 
 // JiraIssue represents Jira issue.
-// Take a special note of the hard-coded field `Customfield10110`
-// which represents the Release Notes custom field.
-// This would be called differently on other Jira accounts
-// so it should be refactored to make the code more generic for all accounts.
+// Take a special note of the field `Customfield10110`
+// which represents the Release Notes custom field in our Jira account.
+// This is called differently on other Jira accounts,
+// so the field is configurable and read from `RawFields`.
 type JiraIssue struct {
 	Expand string `json:"expand"`
 	ID     string `json:"id"`
@@ -60,6 +60,7 @@ type JiraIssue struct {
 		Duedate                       string `json:"duedate"`
 	} `json:"names"`
 	Fields struct {
+		Summary                  string        `json:"summary"`
 		Statuscategorychangedate string        `json:"statuscategorychangedate"`
 		Fixversions              []interface{} `json:"fixVersions"`
 		// NOTE: this should be configurable;
@@ -92,8 +93,8 @@ type JiraIssue struct {
 				Name      string `json:"name"`
 			} `json:"statusCategory"`
 		} `json:"status"`
-		Components            []interface{} `json:"components"`
-		Aggregatetimeestimate interface{}   `json:"aggregatetimeestimate"`
+		Components            []JiraComponent `json:"components"`
+		Aggregatetimeestimate interface{}     `json:"aggregatetimeestimate"`
 		Aggregateprogress     struct {
 			Progress int `json:"progress"`
 			Total    int `json:"total"`
@@ -130,10 +131,19 @@ type JiraIssue struct {
 		Environment          interface{}   `json:"environment"`
 		Duedate              interface{}   `json:"duedate"`
 	} `json:"fields"`
+	// RawFields are all the fields by their ID, e.g. customfield_10110
+	RawFields map[string]json.RawMessage `json:"-"`
+}
+
+// NOTE: the Release Notes custom field in our Jira account
+const defaultJiraReleaseNotesField = "customfield_10110"
+
+type JiraComponent struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
 }
 
 func validateJiraIssue(json JiraIssue) error {
-	validate = validator.New()
 	err := validate.Struct(json)
 	if err != nil {
 
@@ -191,6 +201,12 @@ func getJiraIssue(cfg *Config, key string) (*JiraIssue, error) {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &StatusError{StatusCode: http.StatusNotFound, Err: fmt.Errorf("the Jira issue %s does not exist", key)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get the Jira issue %s: %d %s", key, resp.StatusCode, string(body))
+	}
 	// NOTE: for debugging/testing
 	//os.WriteFile(fmt.Sprintf("./sample-data/jira-%s.json", key), body, 0644)
 
@@ -210,6 +226,15 @@ func parseJiraIssue(jsonData []byte) (JiraIssue, error) {
 		return data, err
 	}
 
+	var raw struct {
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	err = json.Unmarshal(jsonData, &raw)
+	if err != nil {
+		return data, err
+	}
+	data.RawFields = raw.Fields
+
 	err = validateJiraIssue(data)
 
 	return data, err
@@ -220,6 +245,12 @@ func getUniqueJiraIssues(cfg *Config, jiraKeys []string) ([]JiraIssue, error) {
 	jiraIssues := []JiraIssue{}
 	for _, jiraIssueKey := range uniqueJiraKeys {
 		jiraIssue, err := getJiraIssue(cfg, jiraIssueKey)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			// NOTE: e.g. a commit message starting with something like UTF-8, which is not an issue key
+			log.Printf("Skipping %s - %v", jiraIssueKey, err)
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -228,7 +259,24 @@ func getUniqueJiraIssues(cfg *Config, jiraKeys []string) ([]JiraIssue, error) {
 	return jiraIssues, nil
 }
 
-func extractReleaseNotes(jiraIssues []JiraIssue) *Notes {
+// getJiraTextField returns the value of a text field, e.g. customfield_10110,
+// or an empty string if the issue does not have it
+func getJiraTextField(issue JiraIssue, field string) string {
+	raw, ok := issue.RawFields[field]
+	if !ok {
+		if field == defaultJiraReleaseNotesField {
+			return issue.Fields.Customfield10110
+		}
+		return ""
+	}
+	var value string
+	if json.Unmarshal(raw, &value) != nil {
+		return ""
+	}
+	return value
+}
+
+func extractReleaseNotes(jiraIssues []JiraIssue, releaseNotesField string) *Notes {
 	// combinedNotes := make(map[string]string)
 	notes := &Notes{
 		Groups: make(map[string][]string),
@@ -239,8 +287,8 @@ func extractReleaseNotes(jiraIssues []JiraIssue) *Notes {
 		// "h4. Breaking Change\n\n* rename Iotic API methods and objects"
 
 		// TODO: we could validate that the Release Notes field
-		// still corresponds to Customfield10110
-		jiraNotes := issue.Fields.Customfield10110
+		// still corresponds to the configured field using ?expand=names
+		jiraNotes := getJiraTextField(issue, releaseNotesField)
 		if jiraNotes == "" {
 			fmt.Println("- no release notes found")
 			continue
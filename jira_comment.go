@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JiraComments represents a page of comments of a Jira issue
+type JiraComments struct {
+	StartAt    int           `json:"startAt"`
+	MaxResults int           `json:"maxResults"`
+	Total      int           `json:"total"`
+	Comments   []JiraComment `json:"comments"`
+}
+
+// JiraComment represents a single Jira comment;
+// the body is in the Jira wiki markup format (REST API v2)
+type JiraComment struct {
+	ID   string `json:"id,omitempty"`
+	Body string `json:"body"`
+}
+
+func createReleaseComment(title string, version string, timestamp time.Time, link string) string {
+	// NOTE: Jira wiki markup, the link renders as "title version"
+	return fmt.Sprintf("Released in [%s %s|%s] on %s", title, version, link, timestamp.Format("2006-01-02"))
+}
+
+func getJiraComments(cfg *Config, key string) ([]JiraComment, error) {
+	comments := []JiraComment{}
+	for {
+		// see https://developer.atlassian.com/cloud/jira/platform/rest/v2/api-group-issue-comments/#api-rest-api-2-issue-issueidorkey-comment-get
+		apiURL := fmt.Sprintf("%s/rest/api/2/issue/%s/comment?startAt=%d", cfg.JiraUrl, key, len(comments))
+
+		log.Printf("Calling %s", apiURL)
+
+		req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.SetBasicAuth(cfg.JiraUser, cfg.JiraApiKey)
+		req.Header.Add("Content-Type", "application/json")
+		resp, err := cfg.Client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("401 Unauthorized")
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to get comments of %s: %s", key, string(body))
+		}
+
+		page, err := parseJiraComments(body)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, page.Comments...)
+		if len(page.Comments) == 0 || len(comments) >= page.Total {
+			return comments, nil
+		}
+	}
+}
+
+func parseJiraComments(jsonData []byte) (JiraComments, error) {
+	var data JiraComments
+
+	if !isJSON(jsonData) {
+		return data, errors.New("cannot create object - invalid json")
+	}
+
+	err := json.Unmarshal(jsonData, &data)
+	return data, err
+}
+
+func addJiraComment(cfg *Config, key string, comment string) error {
+
+	// see https://developer.atlassian.com/cloud/jira/platform/rest/v2/api-group-issue-comments/#api-rest-api-2-issue-issueidorkey-comment-post
+	apiURL := fmt.Sprintf("%s/rest/api/2/issue/%s/comment", cfg.JiraUrl, key)
+
+	log.Printf("Calling %s", apiURL)
+
+	jsonStr, _ := json.Marshal(&JiraComment{Body: comment})
+	req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth(cfg.JiraUser, cfg.JiraApiKey)
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		response, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("failed to comment on %s: %s", key, string(response))
+	}
+	return nil
+}
+
+// commentOnJiraIssues adds the comment to all the issues,
+// unless the issue already has a comment containing the link,
+// so that re-running the release notes doesn't add duplicate comments
+func commentOnJiraIssues(cfg *Config, jiraIssues []JiraIssue, comment string, link string) error {
+	for _, issue := range jiraIssues {
+		comments, err := getJiraComments(cfg, issue.Key)
+		if err != nil {
+			return err
+		}
+		if hasCommentWithLink(comments, link) {
+			fmt.Printf("%s - already commented\n", issue.Key)
+			continue
+		}
+		err = addJiraComment(cfg, issue.Key, comment)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasCommentWithLink(comments []JiraComment, link string) bool {
+	for _, c := range comments {
+		if strings.Contains(c.Body, link) {
+			return true
+		}
+	}
+	return false
+}
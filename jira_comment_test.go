@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+func TestCreateReleaseComment(t *testing.T) {
+	timestamp := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	want := "Released in [The Best Web 2.0.390|https://example.com/wiki/blog/1] on 2021-03-10"
+	got := createReleaseComment("The Best Web", "2.0.390", timestamp, "https://example.com/wiki/blog/1")
+	if got != want {
+		t.Errorf("expected: %v, got: %v", want, got)
+	}
+}
+
+func TestCommentOnJiraIssuesIsIdempotent(t *testing.T) {
+	link := "https://example.com/wiki/blog/1"
+	existing := map[string][]JiraComment{
+		"JI-1": {{ID: "1", Body: "unrelated"}},
+		"JI-2": {{ID: "2", Body: "Released in [Test v1|" + link + "] on 2021-03-10"}},
+	}
+	posted := []string{}
+
+	cfg := NewDefaultConfig()
+	cfg.Client = &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodPost {
+				posted = append(posted, req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusCreated,
+					Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+				}, nil
+			}
+			key := extractKeyFromJiraURL(req.URL.Path[:len(req.URL.Path)-len("/comment")])
+			json, _ := json.Marshal(&JiraComments{Total: len(existing[key]), Comments: existing[key]})
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(json)),
+			}, nil
+		},
+	}
+
+	issues := []JiraIssue{{Key: "JI-1"}, {Key: "JI-2"}}
+	err := commentOnJiraIssues(cfg, issues, "Released in [Test v1|"+link+"] on 2021-03-10", link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posted) != 1 || posted[0] != "/rest/api/2/issue/JI-1/comment" {
+		t.Errorf("expected a single comment on JI-1, got: %v", posted)
+	}
+}
@@ -118,3 +118,32 @@ func TestExtractHeadings(t *testing.T) {
 		}
 	}
 }
+
+func TestGetJiraTextField(t *testing.T) {
+	validJSON, err := os.ReadFile("./sample-data/jira-JI-1227.json")
+	if err != nil {
+		t.Errorf("could not read file: %s", err)
+	}
+	issue, err := parseJiraIssue(validJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type test struct {
+		field string
+		want  string
+	}
+	tests := []test{
+		{field: "customfield_10014", want: "FO-1921"},
+		{field: "customfield_10110", want: issue.Fields.Customfield10110},
+		{field: "customfield_99999", want: ""},
+		{field: "labels", want: ""},
+	}
+
+	for _, tc := range tests {
+		got := getJiraTextField(issue, tc.field)
+		if tc.want != got {
+			t.Fatalf("%s expected: %v, got: %v", tc.field, tc.want, got)
+		}
+	}
+}
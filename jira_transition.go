@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// JiraTransitionRule moves issues of a project from one status to another,
+// e.g. from "Done" to "Released"; use "*" as the project to match all projects
+type JiraTransitionRule struct {
+	Project string
+	From    string
+	To      string
+}
+
+// JiraTransitions represents the transitions available for a Jira issue
+type JiraTransitions struct {
+	Transitions []JiraTransition `json:"transitions"`
+}
+
+type JiraTransition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	To   struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"to"`
+}
+
+// TransitionReport summarises which issues were (or in a dry run would be) transitioned
+type TransitionReport struct {
+	DryRun       bool
+	Transitioned []string
+	Skipped      []TransitionFailure `json:",omitempty"`
+	Failed       []TransitionFailure `json:",omitempty"`
+}
+
+type TransitionFailure struct {
+	Key    string
+	Reason string
+}
+
+func getJiraProject(key string) string {
+	return strings.SplitN(key, "-", 2)[0]
+}
+
+func findTransitionRule(rules []JiraTransitionRule, issue JiraIssue) (*JiraTransitionRule, bool) {
+	project := getJiraProject(issue.Key)
+	projectHasRules := false
+	for i, rule := range rules {
+		if rule.Project != "*" && !strings.EqualFold(rule.Project, project) {
+			continue
+		}
+		projectHasRules = true
+		if strings.EqualFold(rule.From, issue.Fields.Status.Name) {
+			return &rules[i], true
+		}
+	}
+	return nil, projectHasRules
+}
+
+func getJiraTransitions(cfg *Config, key string) ([]JiraTransition, error) {
+
+	// see https://developer.atlassian.com/cloud/jira/platform/rest/v2/api-group-issues/#api-rest-api-2-issue-issueidorkey-transitions-get
+	apiURL := fmt.Sprintf("%s/rest/api/2/issue/%s/transitions", cfg.JiraUrl, key)
+
+	log.Printf("Calling %s", apiURL)
+
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.SetBasicAuth(cfg.JiraUser, cfg.JiraApiKey)
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("401 Unauthorized")
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get transitions: %s", string(body))
+	}
+
+	obj, err := parseJiraTransitions(body)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Transitions, nil
+}
+
+func parseJiraTransitions(jsonData []byte) (JiraTransitions, error) {
+	var data JiraTransitions
+
+	if !isJSON(jsonData) {
+		return data, errors.New("cannot create object - invalid json")
+	}
+
+	err := json.Unmarshal(jsonData, &data)
+	return data, err
+}
+
+func doJiraTransition(cfg *Config, key string, transitionID string) error {
+
+	// see https://developer.atlassian.com/cloud/jira/platform/rest/v2/api-group-issues/#api-rest-api-2-issue-issueidorkey-transitions-post
+	apiURL := fmt.Sprintf("%s/rest/api/2/issue/%s/transitions", cfg.JiraUrl, key)
+
+	log.Printf("Calling %s", apiURL)
+
+	payload := map[string]interface{}{
+		"transition": map[string]string{"id": transitionID},
+	}
+	jsonStr, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth(cfg.JiraUser, cfg.JiraApiKey)
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		response, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%d %s", resp.StatusCode, string(response))
+	}
+	return nil
+}
+
+// transitionJiraIssues applies the configured transition rules to the issues, or only reports them on a dry run;
+// failures are collected in the report rather than stopping the other issues
+func transitionJiraIssues(cfg *Config, jiraIssues []JiraIssue, dryRun bool) *TransitionReport {
+	report := &TransitionReport{
+		DryRun:       dryRun,
+		Transitioned: []string{},
+	}
+	for _, issue := range jiraIssues {
+		status := issue.Fields.Status.Name
+		rule, projectHasRules := findTransitionRule(cfg.JiraTransitions, issue)
+		if rule == nil {
+			if projectHasRules {
+				report.Skipped = append(report.Skipped, TransitionFailure{
+					Key:    issue.Key,
+					Reason: fmt.Sprintf("no rule for status %q", status),
+				})
+			}
+			continue
+		}
+
+		transitions, err := getJiraTransitions(cfg, issue.Key)
+		if err != nil {
+			report.Failed = append(report.Failed, TransitionFailure{Key: issue.Key, Reason: err.Error()})
+			continue
+		}
+		transition := findTransitionTo(transitions, rule.To)
+		if transition == nil {
+			report.Failed = append(report.Failed, TransitionFailure{
+				Key:    issue.Key,
+				Reason: fmt.Sprintf("no transition from %q to %q available", status, rule.To),
+			})
+			continue
+		}
+
+		if !report.DryRun {
+			err = doJiraTransition(cfg, issue.Key, transition.ID)
+			if err != nil {
+				report.Failed = append(report.Failed, TransitionFailure{Key: issue.Key, Reason: err.Error()})
+				continue
+			}
+		}
+		fmt.Printf("%s - %s -> %s (dry run: %v)\n", issue.Key, status, rule.To, report.DryRun)
+		report.Transitioned = append(report.Transitioned, issue.Key)
+	}
+	return report
+}
+
+func findTransitionTo(transitions []JiraTransition, status string) *JiraTransition {
+	for i, t := range transitions {
+		if strings.EqualFold(t.To.Name, status) {
+			return &transitions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+func newJiraIssueWithStatus(key string, status string) JiraIssue {
+	issue := JiraIssue{Key: key}
+	issue.Fields.Status.Name = status
+	return issue
+}
+
+func mockJiraTransitions(transitioned *[]string) *mocks.MockClient {
+	return &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			json := `{"transitions":[{"id":"31","name":"Release","to":{"id":"10","name":"Released"}}]}`
+			if strings.Contains(req.URL.Path, "/JI-3/") {
+				json = `{"transitions":[{"id":"11","name":"Reopen","to":{"id":"1","name":"To Do"}}]}`
+			}
+			status := http.StatusOK
+			if req.Method == http.MethodPost {
+				*transitioned = append(*transitioned, req.URL.Path)
+				json = ""
+				status = http.StatusNoContent
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(json))),
+			}, nil
+		},
+	}
+}
+
+func TestTransitionJiraIssues(t *testing.T) {
+	transitioned := []string{}
+	cfg := NewDefaultConfig()
+	cfg.Client = mockJiraTransitions(&transitioned)
+	cfg.JiraTransitions = []JiraTransitionRule{{Project: "JI", From: "Done", To: "Released"}}
+
+	issues := []JiraIssue{
+		newJiraIssueWithStatus("JI-1", "Done"),
+		newJiraIssueWithStatus("JI-2", "In Progress"),
+		newJiraIssueWithStatus("JI-3", "Done"),
+		newJiraIssueWithStatus("OTHER-1", "Done"),
+	}
+	report := transitionJiraIssues(cfg, issues, false)
+
+	if !reflect.DeepEqual(report.Transitioned, []string{"JI-1"}) {
+		t.Errorf("unexpected transitioned issues: %v", report.Transitioned)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].Key != "JI-2" {
+		t.Errorf("unexpected skipped issues: %v", report.Skipped)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Key != "JI-3" {
+		t.Errorf("unexpected failed issues: %v", report.Failed)
+	}
+	if !reflect.DeepEqual(transitioned, []string{"/rest/api/2/issue/JI-1/transitions"}) {
+		t.Errorf("unexpected transition requests: %v", transitioned)
+	}
+}
+
+func TestTransitionJiraIssuesDryRun(t *testing.T) {
+	transitioned := []string{}
+	cfg := NewDefaultConfig()
+	cfg.Client = mockJiraTransitions(&transitioned)
+	cfg.JiraTransitions = []JiraTransitionRule{{Project: "*", From: "done", To: "released"}}
+
+	report := transitionJiraIssues(cfg, []JiraIssue{newJiraIssueWithStatus("JI-1", "Done")}, true)
+
+	if !report.DryRun || !reflect.DeepEqual(report.Transitioned, []string{"JI-1"}) {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if len(transitioned) != 0 {
+		t.Errorf("dry run must not transition issues: %v", transitioned)
+	}
+}
+
+func TestGetQueryParamsDryRun(t *testing.T) {
+	query, _ := url.ParseQuery("title=The%20Best%20Web&pipeline=iotic-webbing&counter=390&dryRun=true")
+	params, err := getQueryParamsFromRequest(newRequestLogger(), query)
+	if err != nil || !params.DryRun {
+		t.Errorf("unexpected params: %+v %v", params, err)
+	}
+}
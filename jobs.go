@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+	log "github.com/sirupsen/logrus"
+)
+
+// JobsConfig runs the release notes in the background, see POST /jobs
+type JobsConfig struct {
+	// Workers run the jobs in parallel, 2 by default
+	Workers int
+	// QueueSize is the number of jobs waiting for a worker, 100 by default; POST /jobs returns 503 when it's full
+	QueueSize int
+	// MaxJobs is the number of finished jobs kept, 1000 by default
+	MaxJobs int
+	// StateFile keeps the jobs across restarts, e.g. /var/lib/release-notes/jobs.json;
+	// the jobs are only kept in memory when empty
+	StateFile string
+}
+
+const (
+	defaultJobWorkers   = 2
+	defaultJobQueueSize = 100
+	defaultMaxJobs      = 1000
+)
+
+// the statuses of the jobs and their stages
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+)
+
+// the stages of createReleaseNotes
+const (
+	stageGocd    = "gocd"
+	stageJira    = "jira"
+	stageGate    = "gate"
+	stagePublish = "publish"
+	stageUpdate  = "jira-update"
+)
+
+// Job is a run of createReleaseNotes in the background
+type Job struct {
+	ID     string
+	Status string
+	Params QueryParams
+	Stages []JobStage
+	Result *ReleaseResult `json:",omitempty"`
+	Error  string         `json:",omitempty"`
+	// StatusCode and ErrorBody are the response the synchronous request would have had, e.g. 422 with the gate report
+	StatusCode int         `json:",omitempty"`
+	ErrorBody  interface{} `json:",omitempty"`
+	Created    time.Time
+	Updated    time.Time
+}
+
+type JobStage struct {
+	Name     string
+	Status   string
+	Started  time.Time
+	Finished *time.Time `json:",omitempty"`
+}
+
+// JobQueue runs the jobs with a bounded number of workers
+type JobQueue struct {
+	cfg   *Config
+	run   func(*Config, *QueryParams) (*ReleaseResult, error)
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	queue chan string
+}
+
+func newJobQueue(cfg *Config, run func(*Config, *QueryParams) (*ReleaseResult, error)) (*JobQueue, error) {
+	queueSize := cfg.Jobs.QueueSize
+	if queueSize == 0 {
+		queueSize = defaultJobQueueSize
+	}
+	q := &JobQueue{cfg: cfg, run: run, jobs: map[string]*Job{}, queue: make(chan string, queueSize)}
+	err := q.load()
+	return q, err
+}
+
+// start runs the workers
+func (q *JobQueue) start() {
+	workers := q.cfg.Jobs.Workers
+	if workers == 0 {
+		workers = defaultJobWorkers
+	}
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+}
+
+// submit queues a new job, it fails with 503 when the queue is full
+func (q *JobQueue) submit(params QueryParams) (*Job, error) {
+	now := time.Now().UTC()
+	job := &Job{ID: xid.New().String(), Status: jobQueued, Params: params, Stages: []JobStage{}, Created: now, Updated: now}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case q.queue <- job.ID:
+	default:
+		return nil, &StatusError{StatusCode: http.StatusServiceUnavailable, Err: fmt.Errorf("the job queue is full, try again later")}
+	}
+	q.jobs[job.ID] = job
+	q.save()
+	return job.copy(), nil
+}
+
+// get returns a copy of the job, or nil
+func (q *JobQueue) get(id string) *Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil
+	}
+	return job.copy()
+}
+
+func (job *Job) copy() *Job {
+	c := *job
+	c.Stages = append([]JobStage{}, job.Stages...)
+	return &c
+}
+
+func (q *JobQueue) work() {
+	for id := range q.queue {
+		q.runJob(id)
+	}
+}
+
+func (q *JobQueue) runJob(id string) {
+	q.mu.Lock()
+	job, ok := q.jobs[id]
+	if !ok {
+		q.mu.Unlock()
+		return
+	}
+	job.Status = jobRunning
+	job.Updated = time.Now().UTC()
+	params := job.Params
+	q.save()
+	q.mu.Unlock()
+
+	params.onStage = func(name string) {
+		q.update(job, func() { job.startStage(name) })
+	}
+	logger := log.WithFields(log.Fields{"jobID": id})
+	logger.Infof("Running job for %s/%d", params.Pipeline, params.Counter)
+	result, err := q.run(q.cfg, &params)
+
+	q.update(job, func() {
+		job.Result = result
+		job.Status = jobSucceeded
+		if err != nil {
+			logger.Errorf("Job failed: %v", err)
+			job.Status = jobFailed
+			job.Error = err.Error()
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) {
+				job.StatusCode = statusErr.StatusCode
+				job.ErrorBody = statusErr.Body
+			}
+		}
+		job.finishStage(job.Status)
+		q.prune()
+	})
+}
+
+// update changes the job and persists the jobs
+func (q *JobQueue) update(job *Job, change func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	change()
+	job.Updated = time.Now().UTC()
+	q.save()
+}
+
+func (job *Job) startStage(name string) {
+	job.finishStage(jobSucceeded)
+	job.Stages = append(job.Stages, JobStage{Name: name, Status: jobRunning, Started: time.Now().UTC()})
+}
+
+func (job *Job) finishStage(status string) {
+	if len(job.Stages) == 0 {
+		return
+	}
+	stage := &job.Stages[len(job.Stages)-1]
+	if stage.Status != jobRunning {
+		return
+	}
+	now := time.Now().UTC()
+	stage.Status = status
+	stage.Finished = &now
+}
+
+// prune removes the oldest finished jobs above MaxJobs
+func (q *JobQueue) prune() {
+	maxJobs := q.cfg.Jobs.MaxJobs
+	if maxJobs == 0 {
+		maxJobs = defaultMaxJobs
+	}
+	finished := []*Job{}
+	for _, job := range q.jobs {
+		if job.Status == jobSucceeded || job.Status == jobFailed {
+			finished = append(finished, job)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].Updated.Before(finished[j].Updated) })
+	for i := 0; i < len(finished)-maxJobs; i++ {
+		delete(q.jobs, finished[i].ID)
+	}
+}
+
+// save writes the jobs to the state file, if any; the caller holds the lock
+func (q *JobQueue) save() {
+	if q.cfg.Jobs.StateFile == "" {
+		return
+	}
+	jobs := []*Job{}
+	for _, job := range q.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Created.Before(jobs[j].Created) })
+	content, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		log.Errorf("Could not save the jobs: %v", err)
+		return
+	}
+	// NOTE: write and rename, so that a crash doesn't leave a partial file
+	tmp := q.cfg.Jobs.StateFile + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0644)
+	if err == nil {
+		err = os.Rename(tmp, q.cfg.Jobs.StateFile)
+	}
+	if err != nil {
+		log.Errorf("Could not save the jobs: %v", err)
+	}
+}
+
+// load reads the jobs from the state file and queues the queued ones again
+func (q *JobQueue) load() error {
+	if q.cfg.Jobs.StateFile == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(q.cfg.Jobs.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	jobs := []*Job{}
+	err = json.Unmarshal(content, &jobs)
+	if err != nil {
+		return fmt.Errorf("invalid jobs state file %s: %w", q.cfg.Jobs.StateFile, err)
+	}
+	for _, job := range jobs {
+		q.jobs[job.ID] = job
+		if job.Status == jobRunning {
+			// NOTE: not run again, it might have published some of the release notes already
+			job.Status = jobFailed
+			job.Error = "the job was interrupted by a restart"
+			job.finishStage(jobFailed)
+		}
+		if job.Status != jobQueued {
+			continue
+		}
+		select {
+		case q.queue <- job.ID:
+		default:
+			job.Status = jobFailed
+			job.Error = "the job queue was full after a restart"
+		}
+	}
+	return nil
+}
+
+func handleJobsRequest(w http.ResponseWriter, r *http.Request, jobs *JobQueue) {
+	logger := newRequestLogger()
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	queryParams, err := getQueryParamsFromRequest(logger, r.URL.Query())
+	if err != nil {
+		writeResponseError(w, logger, err)
+		return
+	}
+
+	job, err := jobs.submit(*queryParams)
+	if err != nil {
+		writeResponseError(w, logger, err)
+		return
+	}
+	logger.Infof("Queued job %s", job.ID)
+
+	jsonJob, _ := json.Marshal(job)
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(jsonJob)
+}
+
+func handleJobRequest(w http.ResponseWriter, r *http.Request, jobs *JobQueue) {
+	logger := newRequestLogger()
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	job := jobs.get(strings.TrimPrefix(r.URL.Path, "/jobs/"))
+	if job == nil {
+		writeResponseError(w, logger, &StatusError{StatusCode: http.StatusNotFound, Err: fmt.Errorf("job not found")})
+		return
+	}
+
+	jsonJob, _ := json.Marshal(job)
+	w.Write(jsonJob)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForJob polls the job until it's finished
+func waitForJob(t *testing.T, jobs *JobQueue, id string) *Job {
+	for i := 0; i < 200; i++ {
+		job := jobs.get(id)
+		if job != nil && (job.Status == jobSucceeded || job.Status == jobFailed) {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func jobStages(job *Job) []string {
+	stages := []string{}
+	for _, s := range job.Stages {
+		stages = append(stages, s.Name+" "+s.Status)
+	}
+	return stages
+}
+
+func TestJobQueueRunsJobs(t *testing.T) {
+	cfg := NewDefaultConfig()
+	jobs, err := newJobQueue(cfg, func(cfg *Config, params *QueryParams) (*ReleaseResult, error) {
+		params.stage(stageGocd)
+		params.stage(stageJira)
+		return &ReleaseResult{Notes: &Notes{Groups: map[string][]string{"Bug Fixes": {"* BF1"}}}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs.start()
+
+	req, _ := http.NewRequest(http.MethodPost, "/jobs?title=The+Best+Web&pipeline=iotic-webbing&counter=614", nil)
+	rr := httptest.NewRecorder()
+	handleJobsRequest(rr, req, jobs)
+
+	if status := rr.Code; status != http.StatusAccepted {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusAccepted)
+	}
+	var queued Job
+	err = json.Unmarshal(rr.Body.Bytes(), &queued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.Header().Get("Location") != "/jobs/"+queued.ID || queued.Params.Counter != 614 {
+		t.Errorf("unexpected job: %s %+v", rr.Header().Get("Location"), queued)
+	}
+
+	job := waitForJob(t, jobs, queued.ID)
+	if job.Status != jobSucceeded || job.Result == nil || job.Result.Groups["Bug Fixes"][0] != "* BF1" {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	want := []string{"gocd succeeded", "jira succeeded"}
+	if !reflect.DeepEqual(want, jobStages(job)) {
+		t.Errorf("expected: %v, got: %v", want, jobStages(job))
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "/jobs/"+queued.ID, nil)
+	rr = httptest.NewRecorder()
+	handleJobRequest(rr, req, jobs)
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "/jobs/unknown", nil)
+	rr = httptest.NewRecorder()
+	handleJobRequest(rr, req, jobs)
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestJobFailsWithGate(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Client = newSampleMockClient(t, cfg)
+	cfg.Gate = GateConfig{ProtectedMaterials: []string{"git@github.com:Iotic-Labs/docker-certmgr"}, Enforce: true}
+	jobs, err := newJobQueue(cfg, createReleaseNotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs.start()
+
+	queued, err := jobs.submit(QueryParams{Title: "The Best Web", Pipeline: "iotic-webbing", Counter: 614})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := waitForJob(t, jobs, queued.ID)
+	if job.Status != jobFailed || job.StatusCode != http.StatusUnprocessableEntity || job.ErrorBody == nil {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	want := []string{"gocd succeeded", "jira succeeded", "gate failed"}
+	if !reflect.DeepEqual(want, jobStages(job)) {
+		t.Errorf("expected: %v, got: %v", want, jobStages(job))
+	}
+}
+
+// NOTE: run with -race, the two default workers create release notes at the same time
+func TestJobsRunConcurrently(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Client = newSampleMockClient(t, cfg)
+	started := sync.WaitGroup{}
+	started.Add(2)
+	jobs, err := newJobQueue(cfg, func(cfg *Config, params *QueryParams) (*ReleaseResult, error) {
+		started.Done()
+		started.Wait()
+		return createReleaseNotes(cfg, params)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs.start()
+
+	ids := []string{}
+	for i := 0; i < 2; i++ {
+		queued, err := jobs.submit(QueryParams{Title: "The Best Web", Pipeline: "iotic-webbing", Counter: 614})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, queued.ID)
+	}
+	for _, id := range ids {
+		job := waitForJob(t, jobs, id)
+		if job.Status != jobSucceeded || job.Result == nil {
+			t.Errorf("unexpected job: %+v", job)
+		}
+	}
+}
+
+func TestJobQueueFull(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Jobs = JobsConfig{QueueSize: 1}
+	jobs, err := newJobQueue(cfg, createReleaseNotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = jobs.submit(QueryParams{Pipeline: "iotic-webbing", Counter: 614})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jobs.submit(QueryParams{Pipeline: "iotic-webbing", Counter: 615})
+	statusErr, ok := err.(*StatusError)
+	if !ok || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got: %v", err)
+	}
+}
+
+func TestJobQueuePersistence(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Jobs = JobsConfig{StateFile: filepath.Join(t.TempDir(), "jobs.json")}
+	jobs, err := newJobQueue(cfg, createReleaseNotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued, err := jobs.submit(QueryParams{Pipeline: "iotic-webbing", Counter: 614})
+	if err != nil {
+		t.Fatal(err)
+	}
+	running, err := jobs.submit(QueryParams{Pipeline: "iotic-webbing", Counter: 615})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs.update(jobs.jobs[running.ID], func() { jobs.jobs[running.ID].Status = jobRunning })
+
+	// NOTE: a restart
+	restarted, err := newJobQueue(cfg, createReleaseNotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job := restarted.get(queued.ID); job == nil || job.Status != jobQueued || job.Params.Counter != 614 {
+		t.Errorf("expected the queued job, got: %+v", job)
+	}
+	if job := restarted.get(running.ID); job == nil || job.Status != jobFailed {
+		t.Errorf("expected the interrupted job to fail, got: %+v", job)
+	}
+	if len(restarted.queue) != 1 || <-restarted.queue != queued.ID {
+		t.Errorf("expected only the queued job in the queue")
+	}
+}
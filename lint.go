@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// LintConfig checks the release notes of Jira issues before they are merged, see /lint
+type LintConfig struct {
+	// Headings are the known headings, e.g. [Features, Improvements, Bug Fixes, Breaking Changes];
+	// any heading is allowed when empty
+	Headings []string
+	// InternalURLs are regular expressions of links which must not be published, e.g. github\.com/Iotic-Labs/
+	InternalURLs []string
+	// MaxLineLength is 200 characters by default
+	MaxLineLength int
+}
+
+const defaultMaxLineLength = 200
+
+// the checks of the linter
+const (
+	lintUnknownHeading = "unknown-heading"
+	lintOutsideHeading = "outside-heading"
+	lintEmptyGroup     = "empty-group"
+	lintInternalURL    = "internal-url"
+	lintLongLine       = "long-line"
+)
+
+// LintReport is the result of linting the release notes of one or more Jira issues
+type LintReport struct {
+	Passed bool
+	Issues []LintIssueReport
+}
+
+type LintIssueReport struct {
+	Key      string
+	Summary  string
+	Problems []LintProblem
+}
+
+// LintProblem is a problem on a line of the release notes field (starting at 1)
+type LintProblem struct {
+	Check  string
+	Line   int
+	Text   string
+	Reason string
+}
+
+var linkURL = regexp.MustCompile(`https?://[^\s|\]]+`)
+
+// lintReleaseNotes checks the release notes field line by line, the same way as extractGroups reads it
+func lintReleaseNotes(lint LintConfig, jiraNotes string) []LintProblem {
+	problems := []LintProblem{}
+	maxLineLength := lint.MaxLineLength
+	if maxLineLength == 0 {
+		maxLineLength = defaultMaxLineLength
+	}
+	internalURLs := []*regexp.Regexp{}
+	for _, pattern := range lint.InternalURLs {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("Ignoring invalid internal URL pattern %s: %v", pattern, err)
+			continue
+		}
+		internalURLs = append(internalURLs, r)
+	}
+
+	heading := ""
+	headingLine := 0
+	bulletPoints := 0
+	checkEmptyGroup := func() {
+		if headingLine > 0 && bulletPoints == 0 {
+			problems = append(problems, LintProblem{Check: lintEmptyGroup, Line: headingLine, Text: heading, Reason: "the heading has no bullet points"})
+		}
+	}
+
+	for i, line := range strings.Split(jiraNotes, "\n") {
+		lineNumber := i + 1
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		if utf8.RuneCountInString(line) > maxLineLength {
+			problems = append(problems, LintProblem{Check: lintLongLine, Line: lineNumber, Text: line, Reason: fmt.Sprintf("the line is longer than %d characters", maxLineLength)})
+		}
+
+		header := findHeader(line)
+		if header != "" {
+			checkEmptyGroup()
+			heading, headingLine, bulletPoints = header, lineNumber, 0
+			if len(lint.Headings) > 0 && !containsStringFold(lint.Headings, header) {
+				problems = append(problems, LintProblem{Check: lintUnknownHeading, Line: lineNumber, Text: line, Reason: fmt.Sprintf("the heading is not one of: %s", strings.Join(lint.Headings, ", "))})
+			}
+			continue
+		}
+
+		bulletPoints++
+		if headingLine == 0 {
+			problems = append(problems, LintProblem{Check: lintOutsideHeading, Line: lineNumber, Text: line, Reason: "the line is not under a heading, so it would be in the default Changes group"})
+		}
+		for _, link := range linkURL.FindAllString(line, -1) {
+			for _, r := range internalURLs {
+				if r.MatchString(link) {
+					problems = append(problems, LintProblem{Check: lintInternalURL, Line: lineNumber, Text: line, Reason: fmt.Sprintf("the link %s is internal", link)})
+					break
+				}
+			}
+		}
+	}
+	checkEmptyGroup()
+	return problems
+}
+
+func containsStringFold(items []string, s string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// lintJiraIssues lints the issues of the key or the JQL query
+func lintJiraIssues(cfg *Config, key string, jql string) (*LintReport, error) {
+	var jiraIssues []JiraIssue
+	if key != "" {
+		issue, err := getJiraIssue(cfg, key)
+		if err != nil {
+			return nil, err
+		}
+		jiraIssues = append(jiraIssues, *issue)
+	}
+	if jql != "" {
+		found, err := searchJiraIssues(cfg, jql, []string{"summary", cfg.JiraReleaseNotesField})
+		if err != nil {
+			return nil, err
+		}
+		jiraIssues = append(jiraIssues, found...)
+	}
+
+	report := &LintReport{Passed: true, Issues: []LintIssueReport{}}
+	for _, issue := range jiraIssues {
+		problems := lintReleaseNotes(cfg.Lint, getJiraTextField(issue, cfg.JiraReleaseNotesField))
+		if len(problems) > 0 {
+			report.Passed = false
+		}
+		report.Issues = append(report.Issues, LintIssueReport{Key: issue.Key, Summary: issue.Fields.Summary, Problems: problems})
+	}
+	return report, nil
+}
+
+// JiraSearchResult is a page of the issues found by JQL
+type JiraSearchResult struct {
+	StartAt    int               `json:"startAt"`
+	MaxResults int               `json:"maxResults"`
+	Total      int               `json:"total"`
+	Issues     []json.RawMessage `json:"issues"`
+}
+
+func searchJiraIssues(cfg *Config, jql string, fields []string) ([]JiraIssue, error) {
+	jiraIssues := []JiraIssue{}
+	for {
+		// see https://developer.atlassian.com/cloud/jira/platform/rest/v2/api-group-issue-search/#api-rest-api-2-search-get
+		query := url.Values{}
+		query.Set("jql", jql)
+		query.Set("fields", strings.Join(fields, ","))
+		query.Set("startAt", fmt.Sprintf("%d", len(jiraIssues)))
+		apiURL := fmt.Sprintf("%s/rest/api/2/search?%s", cfg.JiraUrl, query.Encode())
+
+		log.Printf("Calling %s", apiURL)
+
+		req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.SetBasicAuth(cfg.JiraUser, cfg.JiraApiKey)
+		req.Header.Add("Content-Type", "application/json")
+		resp, err := cfg.Client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("401 Unauthorized")
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to search Jira issues: %s", string(body))
+		}
+
+		var page JiraSearchResult
+		err = json.Unmarshal(body, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range page.Issues {
+			issue, err := parseJiraIssue(raw)
+			if err != nil {
+				return nil, err
+			}
+			jiraIssues = append(jiraIssues, issue)
+		}
+		if len(page.Issues) == 0 || len(jiraIssues) >= page.Total {
+			return jiraIssues, nil
+		}
+	}
+}
+
+// printLintReport writes the report for the CLI, one line per problem
+func printLintReport(w io.Writer, report *LintReport) {
+	for _, issue := range report.Issues {
+		fmt.Fprintf(w, "%s %s\n", issue.Key, issue.Summary)
+		if len(issue.Problems) == 0 {
+			fmt.Fprintln(w, "  OK")
+		}
+		for _, p := range issue.Problems {
+			fmt.Fprintf(w, "  line %d: %s - %s\n", p.Line, p.Check, p.Reason)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+func TestLintReleaseNotes(t *testing.T) {
+	lint := LintConfig{
+		Headings:      []string{"Features", "Improvements", "Breaking Changes"},
+		InternalURLs:  []string{`github\.com/Iotic-Labs/`},
+		MaxLineLength: 40,
+	}
+	type test struct {
+		input string
+		want  []string
+	}
+	tests := []test{
+		{input: "h4. Features\n* Feat1\n\nh4. Improvements\n* Impr1", want: []string{}},
+		{input: "", want: []string{}},
+		{input: "* no heading\nh4. features\n* Feat1", want: []string{"outside-heading 1"}},
+		{input: "h4. Features\n\nh4. Fixes\n* Fix1", want: []string{"empty-group 1", "unknown-heading 3"}},
+		{input: "h4. Features", want: []string{"empty-group 1"}},
+		{input: "h4. Features\n* [proto|https://github.com/Iotic-Labs/iotic-service-proto] and [docs|https://docs.iotics.com]", want: []string{"long-line 2", "internal-url 2"}},
+	}
+
+	for _, tc := range tests {
+		got := []string{}
+		for _, p := range lintReleaseNotes(lint, tc.input) {
+			got = append(got, fmt.Sprintf("%s %d", p.Check, p.Line))
+		}
+		if !reflect.DeepEqual(tc.want, got) {
+			t.Fatalf("%q expected: %v, got: %v", tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestHandleLintRequest(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Client = newSampleMockClient(t, cfg)
+
+	type test struct {
+		query  string
+		status int
+	}
+	tests := []test{
+		{query: "?key=JI-1736", status: http.StatusOK},
+		{query: "?key=JI-1227", status: http.StatusUnprocessableEntity},
+		{query: "", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "/lint"+tc.query, nil)
+		rr := httptest.NewRecorder()
+		handleLintRequest(rr, req, cfg)
+
+		if status := rr.Code; status != tc.status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", tc.query, status, tc.status)
+		}
+	}
+}
+
+func TestSearchJiraIssues(t *testing.T) {
+	queries := []string{}
+	cfg := NewDefaultConfig()
+	cfg.Client = &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			queries = append(queries, req.URL.Query().Get("startAt")+" "+req.URL.Query().Get("jql"))
+			key := "JI-1227"
+			if req.URL.Query().Get("startAt") == "1" {
+				key = "JI-1889"
+			}
+			issue, _ := os.ReadFile(fmt.Sprintf("./sample-data/jira-%s.json", key))
+			page := JiraSearchResult{MaxResults: 1, Total: 2, Issues: []json.RawMessage{issue}}
+			return jsonResponse(http.StatusOK, &page), nil
+		},
+	}
+
+	issues, err := searchJiraIssues(cfg, `status = "Ready for Release"`, []string{"summary", "customfield_10110"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[0].Key != "JI-1227" || issues[1].Key != "JI-1889" {
+		t.Errorf("unexpected issues: %v", len(issues))
+	}
+	want := []string{`0 status = "Ready for Release"`, `1 status = "Ready for Release"`}
+	if !reflect.DeepEqual(want, queries) {
+		t.Errorf("expected: %v, got: %v", want, queries)
+	}
+}
+
+func TestRunLintCommand(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Client = newSampleMockClient(t, cfg)
+
+	var stdout, stderr bytes.Buffer
+	code := runLintCommand(cfg, []string{"JI-1736", "JI-1227"}, &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("unexpected exit code: %v %v", code, stderr.String())
+	}
+	lines := strings.Split(stdout.String(), "\n")
+	if !strings.HasPrefix(lines[0], "JI-1736 ") || lines[1] != "  OK" || !strings.HasPrefix(lines[3], "  line 1: outside-heading") {
+		t.Errorf("unexpected output: %v", stdout.String())
+	}
+
+	code = runLintCommand(cfg, []string{}, ioutil.Discard, &stderr)
+	if code != 2 {
+		t.Errorf("unexpected exit code: %v", code)
+	}
+}
+
+func TestHandleLintRequestMissingIssue(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Client = &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(req.URL.Path, "/issue/JI-9999") {
+				return jsonResponse(http.StatusNotFound, map[string]interface{}{"errorMessages": []string{"Issue does not exist or you do not have permission to see it."}}), nil
+			}
+			return jsonResponse(http.StatusInternalServerError, map[string]interface{}{}), nil
+		},
+	}
+
+	type test struct {
+		query  string
+		status int
+	}
+	tests := []test{
+		{query: "?key=JI-9999", status: http.StatusNotFound},
+		{query: "?key=JI-1227", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "/lint"+tc.query, nil)
+		rr := httptest.NewRecorder()
+		handleLintRequest(rr, req, cfg)
+
+		if status := rr.Code; status != tc.status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", tc.query, status, tc.status)
+		}
+	}
+
+	issues, err := getUniqueJiraIssues(cfg, []string{"JI-9999"})
+	if err != nil || len(issues) != 0 {
+		t.Errorf("expected the missing issue to be skipped, got: %v %v", issues, err)
+	}
+}
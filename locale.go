@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// LocaleConfig maps a language to the Jira field with its release notes, e.g. de: customfield_10455;
+// each language is published as a separate Confluence post, linked to the others
+type LocaleConfig struct {
+	Locale string
+	Field  string
+	// Fallback decides what to do with the issues without a translation:
+	// source (use the untranslated release notes, the default), omit (leave the issues out),
+	// skip (don't publish the language) or fail (don't publish the release)
+	Fallback string
+	// ConfluenceSpaceKey and ConfluenceTitle (a template) override the Confluence post of the language,
+	// by default it's the title of the release with the locale, e.g. "… (de)"
+	ConfluenceSpaceKey string
+	ConfluenceTitle    string
+}
+
+// the fallback policies of the locales
+const (
+	localeFallbackSource = "source"
+	localeFallbackOmit   = "omit"
+	localeFallbackSkip   = "skip"
+	localeFallbackFail   = "fail"
+)
+
+// sourceLocaleName is used in the links to the source post, when no locale maps to jiraReleaseNotesField
+const sourceLocaleName = "source"
+
+// Translation links to the Confluence post of another language
+type Translation struct {
+	Locale string
+	Link   string
+}
+
+// LocaleReport lists the issues without a translation and the post of the language
+type LocaleReport struct {
+	Locale    string
+	Field     string
+	Fallback  string
+	Missing   []string
+	Published bool
+	URL       string            `json:",omitempty"`
+	Update    *ConfluenceUpdate `json:",omitempty"`
+}
+
+// LocaleRelease is the release with the release notes of a language
+type LocaleRelease struct {
+	cfg     *Config
+	release *Release
+	report  *LocaleReport
+	post    *ConfluenceBlogPost
+}
+
+// sourceLocale returns the name of the language of jiraReleaseNotesField
+func sourceLocale(cfg *Config) string {
+	for _, l := range cfg.Locales {
+		if l.Field == cfg.JiraReleaseNotesField {
+			return l.Locale
+		}
+	}
+	return sourceLocaleName
+}
+
+// forLocale returns a copy of the config publishing the language to Confluence
+func (cfg *Config) forLocale(l LocaleConfig, pipeline string) *Config {
+	c := *cfg
+	c.JiraReleaseNotesField = l.Field
+	if l.ConfluenceSpaceKey != "" {
+		c.ConfluenceSpaceKey = l.ConfluenceSpaceKey
+	}
+	c.ConfluenceTitle = l.ConfluenceTitle
+	if c.ConfluenceTitle == "" {
+		title := getConfluenceTemplateFor(cfg, pipeline).Title
+		if title == "" {
+			title = defaultConfluenceTitle
+		}
+		c.ConfluenceTitle = fmt.Sprintf("%s (%s)", title, l.Locale)
+	}
+	return &c
+}
+
+// newLocaleReleases extracts the release notes of each language in its own pass
+func newLocaleReleases(cfg *Config, release *Release) ([]*LocaleRelease, []*LocaleReport, error) {
+	locales := []*LocaleRelease{}
+	reports := []*LocaleReport{}
+	failed := false
+	for _, l := range cfg.Locales {
+		if l.Field == cfg.JiraReleaseNotesField {
+			continue
+		}
+		fallback := l.Fallback
+		if fallback == "" {
+			fallback = localeFallbackSource
+		}
+		switch fallback {
+		case localeFallbackSource, localeFallbackOmit, localeFallbackSkip, localeFallbackFail:
+		default:
+			return nil, nil, fmt.Errorf("unknown fallback %s of locale %s", fallback, l.Locale)
+		}
+
+		notes, missing := extractLocaleReleaseNotes(release.Issues, cfg.JiraReleaseNotesField, l.Field, fallback == localeFallbackSource)
+		notes.DependabotChanges = release.Notes.DependabotChanges
+		report := &LocaleReport{Locale: l.Locale, Field: l.Field, Fallback: fallback, Missing: missing}
+		report.Published = len(notes.Groups) > 0 && !(len(missing) > 0 && fallback == localeFallbackSkip)
+		failed = failed || (len(missing) > 0 && fallback == localeFallbackFail)
+		reports = append(reports, report)
+
+		localeRelease := *release
+		localeRelease.Notes = notes
+		localeRelease.Post = nil
+		if report.Published {
+			locales = append(locales, &LocaleRelease{cfg: cfg.forLocale(l, release.Pipeline), release: &localeRelease, report: report})
+		}
+	}
+	if failed {
+		return nil, reports, &StatusError{
+			StatusCode: http.StatusUnprocessableEntity,
+			Err:        fmt.Errorf("some translations of the release notes are missing"),
+			Body:       reports,
+		}
+	}
+
+	return locales, reports, nil
+}
+
+// extractLocaleReleaseNotes returns the release notes of the field and the keys of the issues
+// with release notes in the source field only
+func extractLocaleReleaseNotes(jiraIssues []JiraIssue, sourceField string, field string, useSource bool) (*Notes, []string) {
+	notes := &Notes{Groups: map[string][]string{}}
+	missing := []string{}
+	for _, issue := range jiraIssues {
+		jiraNotes := getJiraTextField(issue, field)
+		if jiraNotes == "" {
+			source := getJiraTextField(issue, sourceField)
+			if source == "" {
+				continue
+			}
+			missing = append(missing, issue.Key)
+			if !useSource {
+				continue
+			}
+			jiraNotes = source
+		}
+		addGroups(notes.Groups, extractGroups(jiraNotes))
+	}
+	return notes, missing
+}
+
+// publishLocales publishes the Confluence post of each language, the source one is published as usual
+func publishLocales(cfg *Config, locales []*LocaleRelease) error {
+	for _, l := range locales {
+		redactor, err := newRedactor(cfg.Redaction, l.release)
+		if err != nil {
+			return err
+		}
+		post, update, err := publishReleaseNotesToConfluence(l.cfg, redactor.releaseFor("confluence"))
+		l.report.Update = update
+		l.post = post
+		if post != nil {
+			l.report.URL = post.URL()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to publish locale %s: %w", l.report.Locale, err)
+		}
+	}
+	return nil
+}
+
+// linkLocales updates the published posts of all the languages, including the source one (if published),
+// with links to each other; NOTE: Confluence dates a blog post by the day it's created,
+// so the links are only known once the posts are published
+func linkLocales(cfg *Config, release *Release, locales []*LocaleRelease) error {
+	linked := []*LocaleRelease{}
+	if release != nil && release.Post != nil {
+		linked = append(linked, &LocaleRelease{cfg: cfg, release: release, report: &LocaleReport{Locale: sourceLocale(cfg)}, post: release.Post})
+	}
+	for _, l := range locales {
+		if l.post != nil {
+			linked = append(linked, l)
+		}
+	}
+	if len(linked) < 2 {
+		return nil
+	}
+
+	links := []Translation{}
+	for _, l := range linked {
+		links = append(links, Translation{Locale: l.report.Locale, Link: l.post.URL()})
+	}
+	for i, l := range linked {
+		l.release.Translations = append(append([]Translation{}, links[:i]...), links[i+1:]...)
+		redactor, err := newRedactor(cfg.Redaction, l.release)
+		if err != nil {
+			return err
+		}
+		post, err := newConfluencePostFor(l.cfg, redactor.releaseFor("confluence"))
+		if err != nil {
+			return err
+		}
+		existing, err := getConfluenceContent(l.cfg, l.post.ID)
+		if err != nil {
+			return err
+		}
+		_, _, err = updateConfluencePost(l.cfg, existing, post)
+		if err != nil {
+			return fmt.Errorf("failed to link locale %s: %w", l.report.Locale, err)
+		}
+	}
+	return nil
+}
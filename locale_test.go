@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+func newLocaleTestIssue(key string, fields map[string]string) JiraIssue {
+	issue := JiraIssue{Key: key, RawFields: map[string]json.RawMessage{}}
+	for field, value := range fields {
+		raw, _ := json.Marshal(value)
+		issue.RawFields[field] = raw
+	}
+	return issue
+}
+
+func newLocaleTestRelease() *Release {
+	release := newTestRelease()
+	release.Post = nil
+	release.Issues = []JiraIssue{
+		newLocaleTestIssue("JI-1", map[string]string{"customfield_10110": "h4. Features\n* Feature1", "customfield_10455": "h4. Funktionen\n* Funktion1"}),
+		newLocaleTestIssue("JI-2", map[string]string{"customfield_10110": "h4. Bug Fixes\n* BF1"}),
+		newLocaleTestIssue("JI-3", map[string]string{}),
+	}
+	return release
+}
+
+func TestNewLocaleReleases(t *testing.T) {
+	tests := []struct {
+		name      string
+		fallback  string
+		want      map[string][]string
+		published bool
+		status    int
+	}{
+		{name: "source", fallback: "", want: map[string][]string{"Funktionen": {"* Funktion1"}, "Bug Fixes": {"* BF1"}}, published: true},
+		{name: "omit", fallback: localeFallbackOmit, want: map[string][]string{"Funktionen": {"* Funktion1"}}, published: true},
+		{name: "skip", fallback: localeFallbackSkip, published: false},
+		{name: "fail", fallback: localeFallbackFail, status: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.ConfluenceSpaceKey = "BLOG"
+			cfg.Locales = []LocaleConfig{
+				{Locale: "en", Field: "customfield_10110"},
+				{Locale: "de", Field: "customfield_10455", Fallback: tt.fallback},
+			}
+			release := newLocaleTestRelease()
+
+			locales, reports, err := newLocaleReleases(cfg, release)
+			if tt.status != 0 {
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.status {
+					t.Fatalf("expected status %d, got: %v", tt.status, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reports) != 1 || !reflect.DeepEqual(reports[0].Missing, []string{"JI-2"}) || reports[0].Published != tt.published {
+				t.Fatalf("unexpected reports: %+v", reports[0])
+			}
+			if !tt.published {
+				if len(locales) != 0 {
+					t.Errorf("expected no published locales, got: %d", len(locales))
+				}
+				return
+			}
+			if !reflect.DeepEqual(tt.want, locales[0].release.Notes.Groups) {
+				t.Errorf("expected: %q, got: %q", tt.want, locales[0].release.Notes.Groups)
+			}
+			if locales[0].cfg.ConfluenceTitle != defaultConfluenceTitle+" (de)" {
+				t.Errorf("unexpected title: %s", locales[0].cfg.ConfluenceTitle)
+			}
+		})
+	}
+}
+
+func TestLinkLocales(t *testing.T) {
+	updates := map[string]string{}
+	cfg := NewDefaultConfig()
+	cfg.ConfluenceSpaceKey = "BLOG"
+	cfg.Locales = []LocaleConfig{{Locale: "en", Field: "customfield_10110"}, {Locale: "de", Field: "customfield_10455"}}
+	cfg.Client = &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			switch {
+			case req.URL.Path == "/wiki/rest/api/contentbody/convert/editor2":
+				var storage ConfluenceStorage
+				body, _ := ioutil.ReadAll(req.Body)
+				json.Unmarshal(body, &storage)
+				return jsonResponse(http.StatusOK, &ConfluenceStorage{Value: "<p>" + storage.Value + "</p>", Representation: "editor2"}), nil
+			case req.Method == http.MethodGet && strings.HasPrefix(req.URL.Path, "/wiki/rest/api/content/"):
+				existing := ConfluenceBlogPost{ID: strings.TrimPrefix(req.URL.Path, "/wiki/rest/api/content/"), Version: ConfluenceVersion{Number: 1}}
+				existing.Body.Editor2 = &ConfluenceStorage{Value: "<p>without links</p>", Representation: "editor2"}
+				return jsonResponse(http.StatusOK, &existing), nil
+			case req.Method == http.MethodPut:
+				var post ConfluencePost
+				body, _ := ioutil.ReadAll(req.Body)
+				json.Unmarshal(body, &post)
+				updates[post.ID] = post.Body.Storage.Value
+				return jsonResponse(http.StatusOK, &ConfluenceBlogPost{ID: post.ID, Version: *post.Version}), nil
+			}
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			return jsonResponse(http.StatusOK, map[string]string{}), nil
+		},
+	}
+
+	newPost := func(id string, webUI string) *ConfluenceBlogPost {
+		post := &ConfluenceBlogPost{ID: id}
+		post.Links.Base = "https://example.com/wiki"
+		post.Links.WebUI = webUI
+		return post
+	}
+	// NOTE: the posts were created on another day than the release
+	release := newLocaleTestRelease()
+	release.Post = newPost("1", "/spaces/BLOG/blog/2021/03/12/1")
+	locales, _, err := newLocaleReleases(cfg, release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locales[0].post = newPost("2", "/spaces/BLOG/blog/2021/03/12/2")
+
+	err = linkLocales(cfg, release, locales)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(updates["1"], "Other languages: [de|https://example.com/wiki/spaces/BLOG/blog/2021/03/12/2]") {
+		t.Errorf("unexpected source post: %s", updates["1"])
+	}
+	if !strings.Contains(updates["2"], "Other languages: [en|https://example.com/wiki/spaces/BLOG/blog/2021/03/12/1]") {
+		t.Errorf("unexpected locale post: %s", updates["2"])
+	}
+
+	// NOTE: a single published post has nothing to link to
+	updates = map[string]string{}
+	err = linkLocales(cfg, nil, locales)
+	if err != nil || len(updates) != 0 {
+		t.Errorf("expected no updates, got: %v %v", updates, err)
+	}
+}
+
+func TestNewLocaleReleasesUnknownFallback(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Locales = []LocaleConfig{{Locale: "ja", Field: "customfield_10456", Fallback: "machine"}}
+	_, _, err := newLocaleReleases(cfg, newLocaleTestRelease())
+	if !ErrorContains(err, "unknown fallback machine of locale ja") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConfluenceWikiMarkupTranslations(t *testing.T) {
+	release := newTestRelease()
+	release.Translations = []Translation{{Locale: "de", Link: "https://example.com/wiki/x/de"}, {Locale: "ja", Link: "https://example.com/wiki/x/ja"}}
+	markup, err := createConfluenceWikiMarkup(ConfluenceTemplate{}, newReleaseData(release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Other languages: [de|https://example.com/wiki/x/de] | [ja|https://example.com/wiki/x/ja]"
+	if !strings.Contains(markup, want) {
+		t.Errorf("expected %q in: %s", want, markup)
+	}
+}
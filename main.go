@@ -1,5 +1,10 @@
 package main
 
+import "os"
+
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:], os.Stdout, os.Stderr))
+	}
 	Serve()
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// The release notes are written in the Jira wiki markup,
+// see https://jira.atlassian.com/secure/WikiRendererHelpAction.jspa
+// these helpers convert a single line to other formats.
+
+var (
+	jiraListItem  = regexp.MustCompile(`^([*#-]+)\s+(.*)$`)
+	jiraLink      = regexp.MustCompile(`\[([^|\]]+)\|([^\]]+)\]`)
+	jiraBareLink  = regexp.MustCompile(`\[((?:https?|mailto):[^|\]]+)\]`)
+	jiraMonospace = regexp.MustCompile(`\{\{(.+?)\}\}`)
+	jiraBold      = regexp.MustCompile(`(^|[^\w*])\*([^*\s][^*]*?)\*($|[^\w*])`)
+)
+
+// splitJiraListItem returns the nesting level of a list item (0 if not a list item) and its text
+func splitJiraListItem(line string) (int, string) {
+	match := jiraListItem.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return 0, strings.TrimSpace(line)
+	}
+	return len(match[1]), match[2]
+}
+
+// jiraToSlack converts a line to the Slack mrkdwn format,
+// see https://api.slack.com/reference/surfaces/formatting
+func jiraToSlack(line string) string {
+	level, text := splitJiraListItem(line)
+
+	text = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+	text = jiraLink.ReplaceAllString(text, "<$2|$1>")
+	text = jiraBareLink.ReplaceAllString(text, "<$1>")
+	text = jiraMonospace.ReplaceAllString(text, "`$1`")
+
+	if level == 0 {
+		return text
+	}
+	return strings.Repeat("    ", level-1) + "• " + text
+}
+
+// jiraToMarkdown converts a line to the (GitHub flavoured) Markdown format
+func jiraToMarkdown(line string) string {
+	level, text := splitJiraListItem(line)
+
+	text = jiraLink.ReplaceAllString(text, "[$1]($2)")
+	text = jiraBareLink.ReplaceAllString(text, "<$1>")
+	text = jiraMonospace.ReplaceAllString(text, "`$1`")
+	text = jiraBold.ReplaceAllString(text, "$1**$2**$3")
+
+	if level == 0 {
+		return text
+	}
+	return strings.Repeat("  ", level-1) + "- " + text
+}
+
+// jiraToText converts a line to plain text, links are written as "text (url)"
+func jiraToText(line string) string {
+	level, text := splitJiraListItem(line)
+
+	text = jiraLink.ReplaceAllString(text, "$1 ($2)")
+	text = jiraBareLink.ReplaceAllString(text, "$1")
+	text = jiraMonospace.ReplaceAllString(text, "$1")
+
+	if level == 0 {
+		return text
+	}
+	return strings.Repeat("  ", level-1) + "- " + text
+}
+
+// safeLinkSchemes are the schemes of the links rendered in HTML, other links (e.g. javascript:) are rendered as text
+var safeLinkSchemes = []string{"http", "https", "mailto"}
+
+func isSafeLink(target string) bool {
+	scheme := strings.SplitN(target, ":", 2)[0]
+	return strings.Contains(target, ":") && containsString(safeLinkSchemes, strings.ToLower(scheme))
+}
+
+// jiraInlineToHTML converts the inline markup of a line to HTML
+func jiraInlineToHTML(text string) string {
+	text = html.EscapeString(text)
+	text = jiraLink.ReplaceAllStringFunc(text, func(link string) string {
+		match := jiraLink.FindStringSubmatch(link)
+		if !isSafeLink(strings.TrimSpace(match[2])) {
+			return match[1]
+		}
+		return fmt.Sprintf(`<a href="%s">%s</a>`, strings.TrimSpace(match[2]), match[1])
+	})
+	text = jiraBareLink.ReplaceAllString(text, `<a href="$1">$1</a>`)
+	text = jiraMonospace.ReplaceAllString(text, "<code>$1</code>")
+	text = jiraBold.ReplaceAllString(text, "$1<strong>$2</strong>$3")
+	return text
+}
+
+// jiraLinesToHTML converts lines to HTML paragraphs and (nested) lists
+func jiraLinesToHTML(lines []string) string {
+	var buf strings.Builder
+	depth := 0
+	for _, line := range lines {
+		level, text := splitJiraListItem(line)
+		for depth > level {
+			buf.WriteString("</li></ul>")
+			depth--
+		}
+		if level > 0 && depth == level {
+			buf.WriteString("</li>")
+		}
+		for depth < level {
+			buf.WriteString("<ul>")
+			depth++
+			if depth < level {
+				buf.WriteString("<li>")
+			}
+		}
+		if level == 0 {
+			buf.WriteString("<p>" + jiraInlineToHTML(text) + "</p>")
+			continue
+		}
+		buf.WriteString("<li>" + jiraInlineToHTML(text))
+	}
+	for depth > 0 {
+		buf.WriteString("</li></ul>")
+		depth--
+	}
+	return buf.String()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestJiraToSlack(t *testing.T) {
+	type test struct {
+		input string
+		want  string
+	}
+	tests := []test{
+		{input: "", want: ""},
+		{input: "My release notes", want: "My release notes"},
+		{input: "* Item 1", want: "• Item 1"},
+		{input: "** Nested item", want: "    • Nested item"},
+		{input: "# Numbered item", want: "• Numbered item"},
+		{input: "*bold* text", want: "*bold* text"},
+		{input: "* a < b & c", want: "• a &lt; b &amp; c"},
+		{input: "see [RPCStatus|https://example.com/rpc]", want: "see <https://example.com/rpc|RPCStatus>"},
+		{input: "see [https://example.com]", want: "see <https://example.com>"},
+		{input: "*** {{code}} - integer", want: "        • `code` - integer"},
+	}
+
+	for _, tc := range tests {
+		got := jiraToSlack(tc.input)
+		if tc.want != got {
+			t.Fatalf("expected: %v, got: %v", tc.want, got)
+		}
+	}
+}
+
+func TestJiraToMarkdown(t *testing.T) {
+	type test struct {
+		input string
+		want  string
+	}
+	tests := []test{
+		{input: "", want: ""},
+		{input: "My release notes", want: "My release notes"},
+		{input: "* Item 1", want: "- Item 1"},
+		{input: "** Nested item", want: "  - Nested item"},
+		{input: "* *bold* text", want: "- **bold** text"},
+		{input: "see [RPCStatus|https://example.com/rpc]", want: "see [RPCStatus](https://example.com/rpc)"},
+		{input: "see [https://example.com]", want: "see <https://example.com>"},
+		{input: "*** {{code}} - integer", want: "    - `code` - integer"},
+	}
+
+	for _, tc := range tests {
+		got := jiraToMarkdown(tc.input)
+		if tc.want != got {
+			t.Fatalf("expected: %v, got: %v", tc.want, got)
+		}
+	}
+}
+
+func TestJiraLinesToHTML(t *testing.T) {
+	type test struct {
+		input []string
+		want  string
+	}
+	tests := []test{
+		{input: []string{}, want: ""},
+		{input: []string{"a < b"}, want: "<p>a &lt; b</p>"},
+		{input: []string{"* one", "* two"}, want: "<ul><li>one</li><li>two</li></ul>"},
+		{input: []string{"* one", "** nested", "* two"}, want: "<ul><li>one<ul><li>nested</li></ul></li><li>two</li></ul>"},
+		{input: []string{"** deep"}, want: "<ul><li><ul><li>deep</li></ul></li></ul>"},
+		{input: []string{"* see [docs|https://example.com]", "text"}, want: `<ul><li>see <a href="https://example.com">docs</a></li></ul><p>text</p>`},
+		{input: []string{"mail [us|mailto:team@example.com]"}, want: `<p>mail <a href="mailto:team@example.com">us</a></p>`},
+		{input: []string{"[x|javascript:alert(1)]"}, want: "<p>x</p>"},
+		{input: []string{"[x|JavaScript:alert(1)] and [y|data:text/html,hi] and [z|/relative]"}, want: "<p>x and y and z</p>"},
+	}
+
+	for _, tc := range tests {
+		got := jiraLinesToHTML(tc.input)
+		if tc.want != got {
+			t.Fatalf("expected: %v, got: %v", tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TriggersConfig starts the release notes from GoCD stage notifications, see POST /notifications
+type TriggersConfig struct {
+	// Rules are the pipeline (glob) and stage pairs which trigger the release notes when the stage passes
+	Rules []TriggerRule
+	// Signed requires the body to be signed with HMAC-SHA256 of the gocdnotificationsecret secret,
+	// sent as "sha256=<hex>" in the SignatureHeader (X-Signature-256 by default)
+	Signed          bool
+	SignatureHeader string
+	Secret          string
+}
+
+type TriggerRule struct {
+	Pipeline string
+	Stage    string
+	// Title of the release notes, the title of the config by default
+	Title string
+}
+
+// maxSeenNotifications is the number of notifications remembered to ignore duplicates
+const maxSeenNotifications = 1000
+
+// StageNotification is a stage status from GoCD
+type StageNotification struct {
+	Pipeline     string
+	Counter      int
+	Stage        string
+	StageCounter int
+	Result       string
+}
+
+// key identifies the stage of the pipeline run, GoCD sends the same notification more than once, e.g. on retries;
+// NOTE: a rerun of the stage (a new stage counter) passing again would publish the release notes twice, so it's the same key
+func (n *StageNotification) key() string {
+	return fmt.Sprintf("%s/%d/%s", n.Pipeline, n.Counter, n.Stage)
+}
+
+// gocdStageStatus is the request of a GoCD notification plugin, e.g. the webhook notifier,
+// see https://plugin-api.gocd.org/current/notifications/#stage-status-changed
+type gocdStageStatus struct {
+	Pipeline struct {
+		Name    string `json:"name"`
+		Counter string `json:"counter"`
+		Stage   struct {
+			Name    string `json:"name"`
+			Counter string `json:"counter"`
+			State   string `json:"state"`
+			Result  string `json:"result"`
+		} `json:"stage"`
+	} `json:"pipeline"`
+}
+
+// genericStageStatus is a flat JSON payload, e.g. from a curl task or another CI tool
+type genericStageStatus struct {
+	Pipeline     string `json:"pipeline"`
+	Counter      int    `json:"counter"`
+	Stage        string `json:"stage"`
+	StageCounter int    `json:"stageCounter"`
+	Result       string `json:"result"`
+}
+
+// parseStageNotification reads the payload of a notification plugin or the generic one
+func parseStageNotification(body []byte) (*StageNotification, error) {
+	var probe struct {
+		Pipeline json.RawMessage `json:"pipeline"`
+	}
+	err := json.Unmarshal(body, &probe)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification: %w", err)
+	}
+	if len(probe.Pipeline) == 0 {
+		return nil, fmt.Errorf("invalid notification: missing pipeline")
+	}
+
+	if probe.Pipeline[0] != '{' {
+		var generic genericStageStatus
+		err = json.Unmarshal(body, &generic)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notification: %w", err)
+		}
+		return &StageNotification{
+			Pipeline:     generic.Pipeline,
+			Counter:      generic.Counter,
+			Stage:        generic.Stage,
+			StageCounter: generic.StageCounter,
+			Result:       generic.Result,
+		}, nil
+	}
+
+	var status gocdStageStatus
+	err = json.Unmarshal(body, &status)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification: %w", err)
+	}
+	counter, err := strconv.Atoi(status.Pipeline.Counter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification: pipeline counter %s", status.Pipeline.Counter)
+	}
+	// NOTE: the stage counter is informational, it only tells the reruns apart
+	stageCounter, _ := strconv.Atoi(status.Pipeline.Stage.Counter)
+	result := status.Pipeline.Stage.Result
+	if result == "" {
+		result = status.Pipeline.Stage.State
+	}
+	return &StageNotification{
+		Pipeline:     status.Pipeline.Name,
+		Counter:      counter,
+		Stage:        status.Pipeline.Stage.Name,
+		StageCounter: stageCounter,
+		Result:       result,
+	}, nil
+}
+
+// findTriggerRule returns the first rule of the pipeline and stage, or nil
+func findTriggerRule(triggers TriggersConfig, n *StageNotification) *TriggerRule {
+	for _, rule := range triggers.Rules {
+		if matchPipeline(rule.Pipeline, n.Pipeline) && strings.EqualFold(rule.Stage, n.Stage) {
+			return &rule
+		}
+	}
+	return nil
+}
+
+func verifyNotificationSignature(triggers TriggersConfig, header http.Header, body []byte) bool {
+	if !triggers.Signed {
+		return true
+	}
+	if !isSecretSet(triggers.Secret) {
+		return false
+	}
+	name := triggers.SignatureHeader
+	if name == "" {
+		name = defaultSignatureHeader
+	}
+	return hmac.Equal([]byte(header.Get(name)), []byte(signWebhookBody(triggers.Secret, body)))
+}
+
+// NotificationResult is the response to a notification
+type NotificationResult struct {
+	Triggered bool
+	Reason    string `json:",omitempty"`
+	Job       *Job   `json:",omitempty"`
+}
+
+// NotificationReceiver queues a job for each passed stage of the triggers, once
+type NotificationReceiver struct {
+	cfg  *Config
+	jobs *JobQueue
+	mu   sync.Mutex
+	seen map[string]bool
+	// order of the seen notifications, to forget the oldest ones
+	order []string
+}
+
+func newNotificationReceiver(cfg *Config, jobs *JobQueue) *NotificationReceiver {
+	return &NotificationReceiver{cfg: cfg, jobs: jobs, seen: map[string]bool{}, order: []string{}}
+}
+
+// receive queues the release notes of the notification, if it's a new passed stage of a trigger
+func (r *NotificationReceiver) receive(n *StageNotification) (*NotificationResult, error) {
+	rule := findTriggerRule(r.cfg.Triggers, n)
+	if rule == nil {
+		return &NotificationResult{Reason: fmt.Sprintf("no trigger for %s/%s", n.Pipeline, n.Stage)}, nil
+	}
+	if !strings.EqualFold(n.Result, "Passed") {
+		return &NotificationResult{Reason: fmt.Sprintf("the stage %s", strings.ToLower(n.Result))}, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := n.key()
+	if r.seen[key] {
+		return &NotificationResult{Reason: fmt.Sprintf("duplicate notification %s", key)}, nil
+	}
+
+	job, err := r.jobs.submit(QueryParams{Title: rule.Title, Pipeline: n.Pipeline, Counter: n.Counter})
+	if err != nil {
+		return nil, err
+	}
+	r.seen[key] = true
+	r.order = append(r.order, key)
+	if len(r.order) > maxSeenNotifications {
+		delete(r.seen, r.order[0])
+		r.order = r.order[1:]
+	}
+	return &NotificationResult{Triggered: true, Job: job}, nil
+}
+
+func handleNotificationRequest(w http.ResponseWriter, r *http.Request, receiver *NotificationReceiver) {
+	logger := newRequestLogger()
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeResponseError(w, logger, err)
+		return
+	}
+	if !verifyNotificationSignature(receiver.cfg.Triggers, r.Header, body) {
+		writeResponseError(w, logger, &StatusError{StatusCode: http.StatusUnauthorized, Err: fmt.Errorf("invalid signature")})
+		return
+	}
+
+	notification, err := parseStageNotification(body)
+	if err != nil {
+		writeResponseError(w, logger, err)
+		return
+	}
+	logger.Infof("Notification: %s/%d %s", notification.key(), notification.StageCounter, notification.Result)
+
+	result, err := receiver.receive(notification)
+	if err != nil {
+		writeResponseError(w, logger, err)
+		return
+	}
+
+	jsonResult, _ := json.Marshal(result)
+	if result.Triggered {
+		w.Header().Set("Location", "/jobs/"+result.Job.ID)
+		w.WriteHeader(http.StatusAccepted)
+	}
+	w.Write(jsonResult)
+}
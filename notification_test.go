@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const pluginNotification = `{
+  "pipeline": {
+    "name": "iotic-webbing",
+    "counter": "614",
+    "group": "iotic",
+    "stage": {
+      "name": "deploy",
+      "counter": "1",
+      "approval-type": "success",
+      "state": "Passed",
+      "result": "Passed",
+      "jobs": []
+    }
+  }
+}`
+
+func TestParseStageNotification(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    StageNotification
+		wantErr string
+	}{
+		{
+			name: "notification plugin",
+			body: pluginNotification,
+			want: StageNotification{Pipeline: "iotic-webbing", Counter: 614, Stage: "deploy", StageCounter: 1, Result: "Passed"},
+		},
+		{
+			name: "generic",
+			body: `{"pipeline": "iotic-webbing", "counter": 614, "stage": "deploy", "result": "Failed"}`,
+			want: StageNotification{Pipeline: "iotic-webbing", Counter: 614, Stage: "deploy", Result: "Failed"},
+		},
+		{
+			name:    "missing pipeline",
+			body:    `{"counter": 614}`,
+			wantErr: "invalid notification: missing pipeline",
+		},
+		{
+			name:    "invalid counter",
+			body:    `{"pipeline": {"name": "iotic-webbing", "counter": "latest"}}`,
+			wantErr: "invalid notification: pipeline counter latest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStageNotification([]byte(tt.body))
+			if !ErrorContains(err, tt.wantErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && *got != tt.want {
+				t.Errorf("expected: %+v, got: %+v", tt.want, *got)
+			}
+		})
+	}
+}
+
+func TestHandleNotificationRequest(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Triggers = TriggersConfig{
+		Rules:  []TriggerRule{{Pipeline: "iotic-*", Stage: "Deploy", Title: "The Best Web"}},
+		Signed: true,
+		Secret: "s3cr3t",
+	}
+	// NOTE: the workers are not started, so the jobs stay queued
+	jobs, err := newJobQueue(cfg, createReleaseNotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := newNotificationReceiver(cfg, jobs)
+
+	send := func(body string, signature string) (int, NotificationResult) {
+		req, _ := http.NewRequest(http.MethodPost, "/notifications", bytes.NewBufferString(body))
+		req.Header.Set(defaultSignatureHeader, signature)
+		rr := httptest.NewRecorder()
+		handleNotificationRequest(rr, req, receiver)
+		var result NotificationResult
+		json.Unmarshal(rr.Body.Bytes(), &result)
+		return rr.Code, result
+	}
+	sign := func(body string) string {
+		return signWebhookBody("s3cr3t", []byte(body))
+	}
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		status    int
+		triggered bool
+		reason    string
+	}{
+		{name: "invalid signature", body: pluginNotification, signature: "sha256=00", status: http.StatusUnauthorized},
+		{name: "passed", body: pluginNotification, signature: sign(pluginNotification), status: http.StatusAccepted, triggered: true},
+		{name: "duplicate", body: pluginNotification, signature: sign(pluginNotification), status: http.StatusOK, reason: "duplicate notification iotic-webbing/614/deploy"},
+		{
+			name:      "failed",
+			body:      `{"pipeline": "iotic-webbing", "counter": 615, "stage": "deploy", "result": "Failed"}`,
+			signature: sign(`{"pipeline": "iotic-webbing", "counter": 615, "stage": "deploy", "result": "Failed"}`),
+			status:    http.StatusOK,
+			reason:    "the stage failed",
+		},
+		{
+			name:      "no trigger",
+			body:      `{"pipeline": "iotic-webbing", "counter": 615, "stage": "build", "result": "Passed"}`,
+			signature: sign(`{"pipeline": "iotic-webbing", "counter": 615, "stage": "build", "result": "Passed"}`),
+			status:    http.StatusOK,
+			reason:    "no trigger for iotic-webbing/build",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, result := send(tt.body, tt.signature)
+			if status != tt.status {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.status)
+			}
+			if status == http.StatusUnauthorized {
+				return
+			}
+			if result.Triggered != tt.triggered || result.Reason != tt.reason {
+				t.Errorf("unexpected result: %+v", result)
+			}
+		})
+	}
+
+	if len(jobs.queue) != 1 {
+		t.Fatalf("expected one job, got: %d", len(jobs.queue))
+	}
+	job := jobs.get(<-jobs.queue)
+	if job.Params.Title != "The Best Web" || job.Params.Pipeline != "iotic-webbing" || job.Params.Counter != 614 {
+		t.Errorf("unexpected job: %+v", job.Params)
+	}
+}
+
+func TestVerifyNotificationSignatureWithoutSecret(t *testing.T) {
+	tests := []struct {
+		secret string
+		want   bool
+	}{
+		{secret: "s3cr3t", want: true},
+		// NOTE: the default of getAPISecret must not be accepted, anyone could sign with it
+		{secret: defaultSecret, want: false},
+		{secret: "", want: false},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		header.Set(defaultSignatureHeader, signWebhookBody(tt.secret, []byte(pluginNotification)))
+		triggers := TriggersConfig{Signed: true, Secret: tt.secret}
+		if got := verifyNotificationSignature(triggers, header, []byte(pluginNotification)); got != tt.want {
+			t.Errorf("%q expected: %v, got: %v", tt.secret, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"path"
+	"time"
+)
+
+// PipelineConfig overrides the global config for the pipelines matching the name,
+// either exactly or by a glob, e.g. "iotic-*"; empty settings keep the global value
+type PipelineConfig struct {
+	Pipeline string
+	// Title is used when the query string has no title
+	Title                  string
+	ConfluenceSpaceKey     string
+	ConfluenceLabels       []string
+	ConfluenceAutoLabels   []string
+	ConfluenceContentType  string
+	ConfluenceParentPageID string
+	ConfluenceParentPath   string
+	// Publishers limits where the release notes are published, e.g. [confluence, slack]
+	Publishers            []string
+	JiraReleaseNotesField string
+	// RequireStage is the stage which has to pass before publishing, see Config
+	RequireStage        string
+	RequireStageTimeout time.Duration
+	ReleaseDateStage    string
+}
+
+// getPipelineConfig returns the section of the pipeline,
+// an exact match wins over the first matching glob
+func getPipelineConfig(pipelines []PipelineConfig, pipeline string) *PipelineConfig {
+	for i := range pipelines {
+		if pipelines[i].Pipeline == pipeline {
+			return &pipelines[i]
+		}
+	}
+	for i := range pipelines {
+		if matchPipeline(pipelines[i].Pipeline, pipeline) {
+			return &pipelines[i]
+		}
+	}
+	return nil
+}
+
+// forPipeline returns a copy of the config with the settings of the pipeline section;
+// the precedence is: query string > pipeline section > global config
+func (cfg *Config) forPipeline(pipeline string) *Config {
+	c := *cfg
+	p := getPipelineConfig(cfg.Pipelines, pipeline)
+	if p == nil {
+		return &c
+	}
+	if p.Title != "" {
+		c.Title = p.Title
+	}
+	if p.ConfluenceSpaceKey != "" {
+		c.ConfluenceSpaceKey = p.ConfluenceSpaceKey
+	}
+	if len(p.ConfluenceLabels) > 0 {
+		c.ConfluenceLabels = p.ConfluenceLabels
+	}
+	if len(p.ConfluenceAutoLabels) > 0 {
+		c.ConfluenceAutoLabels = p.ConfluenceAutoLabels
+	}
+	if p.ConfluenceContentType != "" {
+		c.ConfluenceContentType = p.ConfluenceContentType
+	}
+	if p.ConfluenceParentPageID != "" {
+		c.ConfluenceParentPageID = p.ConfluenceParentPageID
+	}
+	if p.ConfluenceParentPath != "" {
+		c.ConfluenceParentPath = p.ConfluenceParentPath
+	}
+	if len(p.Publishers) > 0 {
+		c.Publishers = p.Publishers
+	}
+	if p.JiraReleaseNotesField != "" {
+		c.JiraReleaseNotesField = p.JiraReleaseNotesField
+	}
+	if p.RequireStage != "" {
+		c.RequireStage = p.RequireStage
+	}
+	if p.RequireStageTimeout != 0 {
+		c.RequireStageTimeout = p.RequireStageTimeout
+	}
+	if p.ReleaseDateStage != "" {
+		c.ReleaseDateStage = p.ReleaseDateStage
+	}
+	return &c
+}
+
+// publishesTo checks the publisher is enabled, all of them are by default
+func (cfg *Config) publishesTo(name string) bool {
+	return len(cfg.Publishers) == 0 || containsString(cfg.Publishers, name)
+}
+
+// matchPipeline matches the pipeline name exactly or by a glob, e.g. "*" or "iotic-*"
+func matchPipeline(pattern string, pipeline string) bool {
+	if pattern == pipeline {
+		return true
+	}
+	ok, _ := path.Match(pattern, pipeline)
+	return ok
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchPipeline(t *testing.T) {
+	type test struct {
+		pattern  string
+		pipeline string
+		want     bool
+	}
+	tests := []test{
+		{pattern: "*", pipeline: "iotic-webbing", want: true},
+		{pattern: "iotic-webbing", pipeline: "iotic-webbing", want: true},
+		{pattern: "iotic-*", pipeline: "iotic-webbing", want: true},
+		{pattern: "iotic-?", pipeline: "iotic-webbing", want: false},
+		{pattern: "other", pipeline: "iotic-webbing", want: false},
+		{pattern: "[", pipeline: "iotic-webbing", want: false},
+	}
+
+	for _, tc := range tests {
+		got := matchPipeline(tc.pattern, tc.pipeline)
+		if tc.want != got {
+			t.Fatalf("%s vs %s expected: %v, got: %v", tc.pattern, tc.pipeline, tc.want, got)
+		}
+	}
+}
+
+func TestConfigForPipeline(t *testing.T) {
+	cfg := &Config{
+		Title:                 "Global",
+		ConfluenceSpaceKey:    "BLOG",
+		JiraReleaseNotesField: defaultJiraReleaseNotesField,
+		Pipelines: []PipelineConfig{
+			{Pipeline: "iotic-*", ConfluenceSpaceKey: "IOTIC", ConfluenceLabels: []string{"iotic"}},
+			{Pipeline: "iotic-webbing", Title: "The Best Web", Publishers: []string{"confluence"}, JiraReleaseNotesField: "customfield_10200"},
+		},
+	}
+
+	webbing := cfg.forPipeline("iotic-webbing")
+	if webbing.Title != "The Best Web" || webbing.ConfluenceSpaceKey != "BLOG" || webbing.JiraReleaseNotesField != "customfield_10200" {
+		t.Errorf("unexpected config for exact match: %+v", webbing)
+	}
+	if !webbing.publishesTo("confluence") || webbing.publishesTo("slack") {
+		t.Errorf("unexpected publishers: %v", webbing.Publishers)
+	}
+
+	space := cfg.forPipeline("iotic-space")
+	if space.Title != "Global" || space.ConfluenceSpaceKey != "IOTIC" || !reflect.DeepEqual(space.ConfluenceLabels, []string{"iotic"}) {
+		t.Errorf("unexpected config for glob match: %+v", space)
+	}
+	if !space.publishesTo("slack") {
+		t.Errorf("all publishers should be enabled by default")
+	}
+
+	other := cfg.forPipeline("other")
+	if other.ConfluenceSpaceKey != "BLOG" || other.JiraReleaseNotesField != defaultJiraReleaseNotesField {
+		t.Errorf("unexpected config without a match: %+v", other)
+	}
+	if cfg.Title != "Global" || cfg.ConfluenceSpaceKey != "BLOG" {
+		t.Errorf("the global config should not change: %+v", cfg)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// the aliases of the counter in the query string and the CLI
+const (
+	counterLatest       = "latest"
+	counterLatestPassed = "latest-passed"
+)
+
+// isCounterAlias checks the counter is latest or latest-passed
+func isCounterAlias(counter string) bool {
+	return counter == counterLatest || counter == counterLatestPassed
+}
+
+// resolveCounter sets the counter of the query from its label or alias, by paging through the history;
+// latest-passed is the latest run whose RequireStage passed, or whose stages all passed
+func resolveCounter(cfg *Config, queryParams *QueryParams) error {
+	if queryParams.Counter != 0 {
+		return nil
+	}
+
+	var match func(run *GocdPipelineHistory) bool
+	var missing string
+	switch {
+	case queryParams.Label != "":
+		match = func(run *GocdPipelineHistory) bool { return run.Label == queryParams.Label }
+		missing = fmt.Sprintf("no run of %s with the label %s", queryParams.Pipeline, queryParams.Label)
+	case queryParams.Alias == counterLatest:
+		match = func(run *GocdPipelineHistory) bool { return true }
+		missing = fmt.Sprintf("no run of %s", queryParams.Pipeline)
+	case queryParams.Alias == counterLatestPassed:
+		match = func(run *GocdPipelineHistory) bool { return isRunPassed(cfg, run) }
+		missing = fmt.Sprintf("no passed run of %s", queryParams.Pipeline)
+	default:
+		return fmt.Errorf("set counter in query string")
+	}
+
+	run, err := findGocdPipelineRun(cfg, queryParams.Pipeline, match)
+	if err != nil {
+		return err
+	}
+	if run == nil {
+		return &StatusError{StatusCode: http.StatusNotFound, Err: fmt.Errorf("%s", missing)}
+	}
+	log.Printf("Resolved %s%s of %s to counter %d", queryParams.Label, queryParams.Alias, queryParams.Pipeline, run.Counter)
+	queryParams.Counter = run.Counter
+	return nil
+}
+
+// findGocdPipelineRun returns the newest run of the pipeline matching, or nil
+func findGocdPipelineRun(cfg *Config, pipeline string, match func(run *GocdPipelineHistory) bool) (*GocdPipelineHistory, error) {
+	page, _, err := getGocdPipelineHistoryPage(cfg, pipeline, "")
+	for i := 1; ; i++ {
+		if err != nil || page == nil {
+			return nil, err
+		}
+		for j := range page.Pipelines {
+			if match(&page.Pipelines[j]) {
+				return &page.Pipelines[j], nil
+			}
+		}
+		if page.Links.Next.Href == "" || i >= maxHistoryPages {
+			return nil, nil
+		}
+		page, _, err = getGocdPipelineHistoryPageURL(cfg, page.Links.Next.Href, "")
+	}
+}
+
+func isRunPassed(cfg *Config, run *GocdPipelineHistory) bool {
+	if cfg.RequireStage != "" {
+		report, _ := getStageReport(run, run.Name, run.Counter, cfg.RequireStage)
+		return report.Reason == ""
+	}
+	for _, s := range run.Stages {
+		if !s.Scheduled || s.Result != "Passed" {
+			return false
+		}
+	}
+	return len(run.Stages) > 0
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestResolveCounter(t *testing.T) {
+	tests := []struct {
+		name         string
+		label        string
+		alias        string
+		requireStage string
+		maxPages     int
+		want         int
+		wantRequests int
+		wantErr      string
+	}{
+		{name: "latest", alias: "latest", want: 105, wantRequests: 1},
+		{name: "latest passed", alias: "latest-passed", want: 103, wantRequests: 2},
+		{name: "latest passed build", alias: "latest-passed", requireStage: "Build", want: 105, wantRequests: 1},
+		{name: "label on a later page", label: "1.42.0", want: 101, wantRequests: 3},
+		{name: "unknown label", label: "2.0.0", wantRequests: 3, wantErr: "no run of iotic-webbing with the label 2.0.0"},
+		{name: "beyond the pages", label: "1.42.0", maxPages: 2, wantRequests: 2, wantErr: "no run of iotic-webbing with the label 1.42.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newPagedHistoryServer(t)
+			defer server.Close()
+			if tt.maxPages != 0 {
+				pages := maxHistoryPages
+				maxHistoryPages = tt.maxPages
+				defer func() { maxHistoryPages = pages }()
+			}
+
+			cfg := NewDefaultConfig()
+			cfg.Client = server.Client()
+			cfg.GocdUrl = server.URL
+			cfg.RequireStage = tt.requireStage
+			queryParams := &QueryParams{Pipeline: "iotic-webbing", Label: tt.label, Alias: tt.alias}
+
+			err := resolveCounter(cfg, queryParams)
+			if !ErrorContains(err, tt.wantErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var statusErr *StatusError
+			if err != nil && (!errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound) {
+				t.Errorf("expected 404, got: %v", err)
+			}
+			if queryParams.Counter != tt.want || *requests != tt.wantRequests {
+				t.Errorf("expected: %d after %d requests, got: %d after %d", tt.want, tt.wantRequests, queryParams.Counter, *requests)
+			}
+		})
+	}
+}
+
+func TestResolveCounterKeepsTheCounter(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.GocdUrl = "http://localhost:0"
+	queryParams := &QueryParams{Pipeline: "iotic-webbing", Counter: 42, Alias: "latest"}
+	err := resolveCounter(cfg, queryParams)
+	if err != nil || queryParams.Counter != 42 {
+		t.Errorf("unexpected result: %d %v", queryParams.Counter, err)
+	}
+}
+
+func TestGetQueryParamsWithLabelOrAlias(t *testing.T) {
+	logger := newRequestLogger()
+
+	tests := []struct {
+		name      string
+		query     string
+		wantLabel string
+		wantAlias string
+		want      int
+		wantErr   string
+	}{
+		{name: "counter", query: "pipeline=iotic-webbing&counter=390", want: 390},
+		{name: "latest", query: "pipeline=iotic-webbing&counter=latest", wantAlias: "latest"},
+		{name: "latest passed", query: "pipeline=iotic-webbing&counter=latest-passed", wantAlias: "latest-passed"},
+		{name: "label", query: "pipeline=iotic-webbing&label=1.42.0", wantLabel: "1.42.0"},
+		{name: "counter and label", query: "pipeline=iotic-webbing&counter=390&label=1.42.0", wantLabel: "1.42.0", want: 390},
+		{name: "unknown alias", query: "pipeline=iotic-webbing&counter=newest", wantErr: "could not process counter"},
+		{name: "no counter", query: "pipeline=iotic-webbing", wantErr: "could not process counter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			params, err := getQueryParamsFromRequest(logger, query)
+			if !ErrorContains(err, tt.wantErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if params.Counter != tt.want || params.Label != tt.wantLabel || params.Alias != tt.wantAlias {
+				t.Errorf("unexpected params: %+v", params)
+			}
+		})
+	}
+}
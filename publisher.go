@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Release holds everything known about a single release,
+// it is passed to all the publishers
+type Release struct {
+	Title     string
+	Pipeline  string
+	Counter   int
+	Version   string
+	Timestamp time.Time
+	Notes     *Notes
+	Issues    []JiraIssue
+	// Comparison holds all the changes (commits) of the release
+	Comparison *GocdPipelineComparison
+	// Post is the Confluence blog post with the release notes
+	Post *ConfluenceBlogPost
+	// Translations link to the Confluence posts of the other languages, see LocaleConfig
+	Translations []Translation
+}
+
+// Publisher sends the release notes somewhere else than Confluence,
+// it runs after the Confluence blog post has been created
+type Publisher interface {
+	Name() string
+	Publish(cfg *Config, release *Release) error
+}
+
+// ReleaseData is the data available to user-supplied templates
+type ReleaseData struct {
+	Title     string
+	Pipeline  string
+	Counter   int
+	Version   string
+	Timestamp time.Time
+	Groups    []Group
+	Issues    []IssueData
+	// Authors of all the commits in the release
+	Authors   []string
+	Materials []MaterialData
+	// URL links to the Confluence blog post, if any
+	URL string
+	// Translations link to the Confluence posts of the other languages, if any
+	Translations []Translation
+}
+
+type IssueData struct {
+	Key        string
+	Summary    string
+	Type       string
+	Status     string
+	Components []string
+}
+
+type MaterialData struct {
+	Type        string
+	Description string
+	URL         string
+	Branch      string
+	Revisions   []RevisionData
+}
+
+type RevisionData struct {
+	Revision string
+	Author   string
+	Message  string
+}
+
+func getPublishers(cfg *Config) []Publisher {
+	configured := []Publisher{}
+	if len(cfg.Slack.Routes) > 0 {
+		configured = append(configured, &SlackPublisher{})
+	}
+	if len(cfg.Webhooks) > 0 {
+		configured = append(configured, &WebhookPublisher{})
+	}
+	if len(cfg.Email.Routes) > 0 {
+		configured = append(configured, &EmailPublisher{})
+	}
+	if len(cfg.Github.Routes) > 0 {
+		configured = append(configured, &GithubPublisher{})
+	}
+	if cfg.Site.Dir != "" {
+		configured = append(configured, &SitePublisher{})
+	}
+	publishers := []Publisher{}
+	for _, p := range configured {
+		if cfg.publishesTo(p.Name()) {
+			publishers = append(publishers, p)
+		}
+	}
+	return publishers
+}
+
+func newReleaseData(release *Release) *ReleaseData {
+	data := &ReleaseData{
+		Title:     release.Title,
+		Pipeline:  release.Pipeline,
+		Counter:   release.Counter,
+		Version:   release.Version,
+		Timestamp: release.Timestamp,
+		Groups:    sortedGroups(release.Notes),
+		Issues:    []IssueData{},
+	}
+	for _, issue := range release.Issues {
+		issueData := IssueData{
+			Key:        issue.Key,
+			Summary:    issue.Fields.Summary,
+			Type:       issue.Fields.Issuetype.Name,
+			Status:     issue.Fields.Status.Name,
+			Components: []string{},
+		}
+		for _, c := range issue.Fields.Components {
+			issueData.Components = append(issueData.Components, c.Name)
+		}
+		data.Issues = append(data.Issues, issueData)
+	}
+	data.Authors, data.Materials = getMaterialData(release.Comparison)
+	if release.Post != nil {
+		data.URL = release.Post.URL()
+	}
+	data.Translations = release.Translations
+	return data
+}
+
+func getMaterialData(comparison *GocdPipelineComparison) ([]string, []MaterialData) {
+	authors := []string{}
+	materials := []MaterialData{}
+	if comparison == nil {
+		return authors, materials
+	}
+	for _, change := range comparison.Changes {
+		material := MaterialData{
+			Type:        change.Material.Type,
+			Description: change.Material.Attributes.Description,
+			URL:         change.Material.Attributes.URL,
+			Branch:      change.Material.Attributes.Branch,
+			Revisions:   []RevisionData{},
+		}
+		for _, r := range change.Revision {
+			revision := RevisionData{
+				Revision: r.RevisionSha,
+				Author:   r.ModifiedBy,
+				Message:  r.CommitMessage,
+			}
+			if change.Material.Type == "dependency" {
+				revision.Revision = r.Revision
+			}
+			if revision.Author != "" {
+				authors = append(authors, revision.Author)
+			}
+			material.Revisions = append(material.Revisions, revision)
+		}
+		materials = append(materials, material)
+	}
+	return unique(authors), materials
+}
+
+// templateFuncs are available in all user-supplied templates, e.g.
+// {{ .Title | json }} or {{ range .BulletPoints }}{{ markdown . }}{{ end }}
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join":     strings.Join,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"markdown": jiraToMarkdown,
+	"markdownLines": func(lines []string) string {
+		converted := []string{}
+		for _, l := range lines {
+			converted = append(converted, jiraToMarkdown(l))
+		}
+		return strings.Join(converted, "\n")
+	},
+}
+
+// htmlTemplateFuncs are available in the HTML templates, e.g. {{ jiraHTML .BulletPoints }}
+var htmlTemplateFuncs = htmltemplate.FuncMap{
+	"jiraHTML": func(lines []string) htmltemplate.HTML {
+		return htmltemplate.HTML(jiraLinesToHTML(lines))
+	},
+}
+
+// sortedGroups returns the groups of release notes sorted by their name,
+// so that the publishers produce a stable output
+func sortedGroups(notes *Notes) []Group {
+	groups := []Group{}
+	for name, bulletPoints := range notes.Groups {
+		groups = append(groups, Group{Name: name, BulletPoints: bulletPoints})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
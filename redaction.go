@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RedactionRules strip internal content from the release notes
+// before they are sent to the publishers of the rule set
+type RedactionRules struct {
+	Name string
+	// Publishers use this rule set, e.g. [email, site]; "confluence" is a publisher too
+	Publishers []string
+	// AllowedLinkDomains keep only the links to these domains (and their subdomains),
+	// the other links are replaced by their text; all links are kept when empty
+	AllowedLinkDomains []string
+	// BlockedLinks are regular expressions of links which are replaced by their text
+	BlockedLinks []string
+	// Patterns are regular expressions of text which is removed
+	Patterns []string
+	// RemovePanels are the titles of panels which are removed, e.g. {panel:title=Internal}…{panel}
+	RemovePanels []string
+	// MaskHosts are globs of host names which are masked, e.g. *.corp.example.com
+	MaskHosts []string
+}
+
+const maskedHost = "[internal host]"
+
+// RedactionReport lists what was redacted by a rule set
+type RedactionReport struct {
+	RuleSet    string
+	Publishers []string
+	Redactions []Redaction
+}
+
+type Redaction struct {
+	Group    string
+	Rule     string
+	Original string
+}
+
+var (
+	plainLink = regexp.MustCompile(`https?://[^\s|\]]+`)
+	hostName  = regexp.MustCompile(`\b[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+\b`)
+	panelEnd  = regexp.MustCompile(`\{panel\}`)
+	panelHead = regexp.MustCompile(`^\s*\{panel:([^}]*)\}`)
+)
+
+// Redactor keeps the redacted release notes of each rule set
+type Redactor struct {
+	release *Release
+	rules   []RedactionRules
+	notes   map[string]*Notes
+	Reports []RedactionReport
+}
+
+func newRedactor(rules []RedactionRules, release *Release) (*Redactor, error) {
+	redactor := &Redactor{release: release, rules: rules, notes: map[string]*Notes{}, Reports: []RedactionReport{}}
+	for _, r := range rules {
+		notes, report, err := redactNotes(r, release.Notes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction rules %s: %w", r.Name, err)
+		}
+		redactor.notes[r.Name] = notes
+		redactor.Reports = append(redactor.Reports, *report)
+	}
+	return redactor, nil
+}
+
+// releaseFor returns the release with the notes redacted by the first rule set of the publisher
+// NOTE: it's a copy, so that the publishers still see e.g. the Post set later on the release
+func (r *Redactor) releaseFor(publisher string) *Release {
+	for _, rules := range r.rules {
+		if containsString(rules.Publishers, publisher) {
+			release := *r.release
+			release.Notes = r.notes[rules.Name]
+			return &release
+		}
+	}
+	return r.release
+}
+
+// afterPanelEnd returns the text after the closing {panel} of the line, e.g. {panel:title=Internal}text{panel},
+// or whether the panel is still open
+func afterPanelEnd(line string) (string, bool) {
+	end := panelEnd.FindStringIndex(line)
+	if end == nil {
+		return "", true
+	}
+	return line[end[1]:], false
+}
+
+func redactNotes(rules RedactionRules, notes *Notes) (*Notes, *RedactionReport, error) {
+	blockedLinks, err := compilePatterns(rules.BlockedLinks)
+	if err != nil {
+		return nil, nil, err
+	}
+	patterns, err := compilePatterns(rules.Patterns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &RedactionReport{RuleSet: rules.Name, Publishers: rules.Publishers, Redactions: []Redaction{}}
+	redacted := &Notes{DependabotChanges: notes.DependabotChanges, Groups: map[string][]string{}}
+	for _, g := range sortedGroups(notes) {
+		lines := []string{}
+		inPanel := false
+		for _, line := range g.BulletPoints {
+			if match := panelHead.FindStringSubmatchIndex(line); !inPanel && match != nil && isRemovedPanel(rules, line[match[2]:match[3]]) {
+				report.add(g.Name, "panel", line)
+				line, inPanel = afterPanelEnd(line[match[1]:])
+			} else if inPanel {
+				report.add(g.Name, "panel", line)
+				line, inPanel = afterPanelEnd(line)
+			}
+			// NOTE: the text after the closing {panel} on the same line is kept
+			if inPanel {
+				continue
+			}
+
+			original := line
+			line = redactLinks(rules, blockedLinks, line)
+			if line != original {
+				report.add(g.Name, "link", original)
+			}
+			for _, p := range patterns {
+				if p.MatchString(line) {
+					report.add(g.Name, "pattern", line)
+					line = p.ReplaceAllString(line, "")
+				}
+			}
+			if masked := maskHosts(rules.MaskHosts, line); masked != line {
+				report.add(g.Name, "host", line)
+				line = masked
+			}
+
+			// NOTE: drop the bullet points with nothing left in them
+			if _, text := splitJiraListItem(line); text == "" {
+				continue
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) > 0 {
+			redacted.Groups[g.Name] = lines
+		}
+	}
+	return redacted, report, nil
+}
+
+func (report *RedactionReport) add(group string, rule string, original string) {
+	report.Redactions = append(report.Redactions, Redaction{Group: group, Rule: rule, Original: original})
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	result := []*regexp.Regexp{}
+	for _, p := range patterns {
+		r, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// isRemovedPanel checks the parameters of the panel, e.g. title=Internal|borderStyle=dashed
+func isRemovedPanel(rules RedactionRules, params string) bool {
+	for _, param := range strings.Split(params, "|") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) == "title" && containsStringFold(rules.RemovePanels, strings.TrimSpace(kv[1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactLinks replaces [text|link] with the text and removes [link] and bare links
+func redactLinks(rules RedactionRules, blockedLinks []*regexp.Regexp, line string) string {
+	line = jiraLink.ReplaceAllStringFunc(line, func(s string) string {
+		match := jiraLink.FindStringSubmatch(s)
+		if isAllowedLink(rules, blockedLinks, match[2]) {
+			return s
+		}
+		return match[1]
+	})
+	line = jiraBareLink.ReplaceAllStringFunc(line, func(s string) string {
+		if isAllowedLink(rules, blockedLinks, jiraBareLink.FindStringSubmatch(s)[1]) {
+			return s
+		}
+		return ""
+	})
+	return plainLink.ReplaceAllStringFunc(line, func(s string) string {
+		if isAllowedLink(rules, blockedLinks, s) {
+			return s
+		}
+		return ""
+	})
+}
+
+func isAllowedLink(rules RedactionRules, blockedLinks []*regexp.Regexp, link string) bool {
+	for _, r := range blockedLinks {
+		if r.MatchString(link) {
+			return false
+		}
+	}
+	if len(rules.AllowedLinkDomains) == 0 || strings.HasPrefix(link, "mailto:") {
+		return true
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range rules.AllowedLinkDomains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func maskHosts(patterns []string, line string) string {
+	if len(patterns) == 0 {
+		return line
+	}
+	return hostName.ReplaceAllStringFunc(line, func(host string) string {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(host)); ok {
+				return maskedHost
+			}
+		}
+		return host
+	})
+}
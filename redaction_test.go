@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRedactNotes(t *testing.T) {
+	validJSON, err := os.ReadFile("./sample-data/jira-JI-1889.json")
+	if err != nil {
+		t.Errorf("could not read file: %s", err)
+	}
+	issue, err := parseJiraIssue(validJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := extractReleaseNotes([]JiraIssue{issue}, defaultJiraReleaseNotesField)
+	notes.Groups["Bug Fixes"] = []string{
+		"* Fixed retries to db01.corp.iotic.com",
+		"{panel:title=Internal|borderStyle=dashed}",
+		"* Restart the brokers after the upgrade",
+		"{panel}",
+		"* Fixed token=abc123 leaking into logs",
+		"* see [https://github.com/Iotic-Labs/iotic-host/pull/1]",
+	}
+
+	rules := RedactionRules{
+		Name:               "customer",
+		AllowedLinkDomains: []string{"github.io"},
+		Patterns:           []string{`token=\S+ `},
+		RemovePanels:       []string{"internal"},
+		MaskHosts:          []string{"*.corp.iotic.com"},
+	}
+	redacted, report, err := redactNotes(rules, notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"Improvements": {"* STOMP error frame body now contains JSON-encoded RPCStatus. See example in iotic-host tests how it can be decoded."},
+		"Bug Fixes": {
+			"* Fixed retries to [internal host]",
+			"* Fixed leaking into logs",
+			"* see ",
+		},
+	}
+	if !reflect.DeepEqual(want["Improvements"], redacted.Groups["Improvements"]) || !reflect.DeepEqual(want["Bug Fixes"], redacted.Groups["Bug Fixes"]) {
+		t.Errorf("expected: %q, got: %q", want, redacted.Groups)
+	}
+	if !reflect.DeepEqual(notes.Groups["Breaking Changes"], redacted.Groups["Breaking Changes"]) {
+		t.Errorf("the allowed link should be kept: %q", redacted.Groups["Breaking Changes"])
+	}
+
+	rulesApplied := []string{}
+	for _, r := range report.Redactions {
+		rulesApplied = append(rulesApplied, r.Group+" "+r.Rule)
+	}
+	wantRules := []string{
+		"Bug Fixes host", "Bug Fixes panel", "Bug Fixes panel", "Bug Fixes panel",
+		"Bug Fixes pattern", "Bug Fixes link", "Improvements link",
+	}
+	if !reflect.DeepEqual(wantRules, rulesApplied) {
+		t.Errorf("expected: %v, got: %v", wantRules, rulesApplied)
+	}
+	if len(notes.Groups["Bug Fixes"]) != 6 {
+		t.Errorf("the original notes should not change: %q", notes.Groups["Bug Fixes"])
+	}
+}
+
+func TestRedactNotesPanelsOnOneLine(t *testing.T) {
+	notes := &Notes{Groups: map[string][]string{"Bug Fixes": {
+		"{panel:title=Internal}Restart the brokers{panel}",
+		"* Fixed the retries",
+		"{panel:title=Internal}",
+		"* Rotate the keys{panel}",
+		"* Fixed the login",
+		"{panel:title=Internal}drop the cache{panel} * Fixed the logout",
+		"{panel:title=Public}Upgrade notes{panel}",
+	}}}
+	rules := RedactionRules{Name: "customer", RemovePanels: []string{"internal"}}
+	redacted, report, err := redactNotes(rules, notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"* Fixed the retries", "* Fixed the login", " * Fixed the logout", "{panel:title=Public}Upgrade notes{panel}"}
+	if !reflect.DeepEqual(want, redacted.Groups["Bug Fixes"]) {
+		t.Errorf("expected: %q, got: %q", want, redacted.Groups["Bug Fixes"])
+	}
+	if len(report.Redactions) != 4 {
+		t.Errorf("unexpected redactions: %+v", report.Redactions)
+	}
+}
+
+func TestRedactorReleaseFor(t *testing.T) {
+	release := newTestRelease()
+	redactor, err := newRedactor([]RedactionRules{
+		{Name: "customer", Publishers: []string{"email", "site"}, BlockedLinks: []string{`example\.com/rpc`}},
+	}, release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if redactor.releaseFor("slack") != release {
+		t.Errorf("slack should get the original release")
+	}
+	site := redactor.releaseFor("site")
+	if site == release || !reflect.DeepEqual(site.Notes.Groups["Improvements"], []string{"* Impr1", "* RPCStatus"}) {
+		t.Errorf("unexpected notes for site: %q", site.Notes.Groups)
+	}
+	if site.Post != release.Post {
+		t.Errorf("the redacted release should keep the post")
+	}
+
+	_, err = newRedactor([]RedactionRules{{Name: "invalid", Patterns: []string{"("}}}, release)
+	if !ErrorContains(err, "invalid redaction rules invalid") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
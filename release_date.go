@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	// NOTE: embeds the timezone database, the container image has none
+	_ "time/tzdata"
+)
+
+// getReleaseTimestamp returns the date of the release in the configured timezone:
+// the completion time of the jobs of ReleaseDateStage, or when the pipeline was scheduled
+func getReleaseTimestamp(cfg *Config, pipeline string, counter int, history *GocdPipelineHistory) (time.Time, error) {
+	location := time.Local
+	if cfg.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timezone %s: %w", cfg.Timezone, err)
+		}
+	}
+	if cfg.ReleaseDateStage == "" {
+		return convertGocdTimestampToGo(history.ScheduledDate).In(location), nil
+	}
+
+	stageCounter := ""
+	for _, s := range history.Stages {
+		if strings.EqualFold(s.Name, cfg.ReleaseDateStage) && s.Scheduled {
+			stageCounter = s.Counter
+		}
+	}
+	if stageCounter == "" {
+		return time.Time{}, fmt.Errorf("the stage %s of %s/%d has not run, so the release has no date", cfg.ReleaseDateStage, pipeline, counter)
+	}
+	stage, err := getGocdStageInstance(cfg, pipeline, counter, cfg.ReleaseDateStage, stageCounter)
+	if err != nil {
+		return time.Time{}, err
+	}
+	completed, ok := getStageCompletionTime(stage)
+	if !ok {
+		// NOTE: the stage is still running, e.g. when a task of the stage itself calls this service,
+		// so the date of the release is the latest transition of its jobs, after any manual approval
+		latest, ok := getStageLatestTransitionTime(stage)
+		if !ok {
+			return time.Time{}, fmt.Errorf("the jobs of the stage %s of %s/%d have not started, so the release has no date", cfg.ReleaseDateStage, pipeline, counter)
+		}
+		log.Printf("The stage %s of %s/%d has not completed, dating the release by its latest job transition", cfg.ReleaseDateStage, pipeline, counter)
+		return latest.In(location), nil
+	}
+	return completed.In(location), nil
+}
+
+// getStageCompletionTime returns when the last job of the stage completed, if they all have
+func getStageCompletionTime(stage *GocdStageInstance) (time.Time, bool) {
+	var completed int64
+	for _, job := range stage.Jobs {
+		jobCompleted := int64(0)
+		for _, transition := range job.JobStateTransitions {
+			if transition.State == "Completed" {
+				jobCompleted = transition.StateChangeTime
+			}
+		}
+		if jobCompleted == 0 {
+			return time.Time{}, false
+		}
+		if jobCompleted > completed {
+			completed = jobCompleted
+		}
+	}
+	if completed == 0 {
+		return time.Time{}, false
+	}
+	// NOTE: GoCD times are milliseconds since the epoch
+	return time.UnixMilli(completed), true
+}
+
+// getStageLatestTransitionTime returns the latest state change of the jobs of the stage, e.g. Building
+func getStageLatestTransitionTime(stage *GocdStageInstance) (time.Time, bool) {
+	var latest int64
+	for _, job := range stage.Jobs {
+		for _, transition := range job.JobStateTransitions {
+			if transition.StateChangeTime > latest {
+				latest = transition.StateChangeTime
+			}
+		}
+	}
+	if latest == 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(latest), true
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+// newStageInstance returns a stage with a job completed at each of the times (milliseconds), 0 for a running job
+func newStageInstance(t *testing.T, completed ...int64) *GocdStageInstance {
+	jobs := []interface{}{}
+	for i, c := range completed {
+		transitions := []interface{}{map[string]interface{}{"state": "Scheduled", "state_change_time": 1615391237492}}
+		if c != 0 {
+			transitions = append(transitions, map[string]interface{}{"state": "Completed", "state_change_time": c})
+		}
+		jobs = append(jobs, map[string]interface{}{"name": fmt.Sprintf("job%d", i), "job_state_transitions": transitions})
+	}
+	content, _ := json.Marshal(map[string]interface{}{"name": "package", "counter": "2", "jobs": jobs})
+	var stage GocdStageInstance
+	err := json.Unmarshal(content, &stage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &stage
+}
+
+func TestGetStageCompletionTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		completed []int64
+		want      int64
+		wantOK    bool
+	}{
+		{name: "one job", completed: []int64{1616891400000}, want: 1616891400000, wantOK: true},
+		{name: "the last job", completed: []int64{1616891400000, 1616895000000}, want: 1616895000000, wantOK: true},
+		{name: "running", completed: []int64{1616891400000, 0}},
+		{name: "no jobs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := getStageCompletionTime(newStageInstance(t, tt.completed...))
+			if ok != tt.wantOK || (ok && got.UnixMilli() != tt.want) {
+				t.Errorf("expected: %v %v, got: %v %v", tt.want, tt.wantOK, got.UnixMilli(), ok)
+			}
+		})
+	}
+}
+
+func TestGetReleaseTimestamp(t *testing.T) {
+	history := readSampleHistory(t, "Passed", "Passed")
+
+	tests := []struct {
+		name      string
+		timezone  string
+		stage     string
+		completed []int64
+		wantTitle string
+		wantTime  string
+		wantErr   string
+	}{
+		{
+			name:      "scheduled date",
+			timezone:  "UTC",
+			wantTitle: "The Best Web Release Notes 2.0.390 - 2021-03-10",
+			wantTime:  "2021-03-10T15:47:17Z",
+		},
+		{
+			// 2021-03-27 23:30 UTC, the day before the clocks go forward
+			name:      "before DST",
+			timezone:  "Europe/Berlin",
+			stage:     "package",
+			completed: []int64{1616887800000},
+			wantTitle: "The Best Web Release Notes 2.0.390 - 2021-03-28",
+			wantTime:  "2021-03-28T00:30:00+01:00",
+		},
+		{
+			// 2021-03-28 22:30 UTC, after the clocks went forward
+			name:      "after DST",
+			timezone:  "Europe/Berlin",
+			stage:     "package",
+			completed: []int64{1616970600000},
+			wantTitle: "The Best Web Release Notes 2.0.390 - 2021-03-29",
+			wantTime:  "2021-03-29T00:30:00+02:00",
+		},
+		{
+			// 2021-10-31 00:30 UTC, before the clocks go back in the US
+			name:      "US",
+			timezone:  "America/New_York",
+			stage:     "package",
+			completed: []int64{1635640200000},
+			wantTitle: "The Best Web Release Notes 2.0.390 - 2021-10-30",
+			wantTime:  "2021-10-30T20:30:00-04:00",
+		},
+		{
+			// 2021-03-27 23:30 UTC, a job of the stage is still running
+			name:      "stage running",
+			timezone:  "UTC",
+			stage:     "package",
+			completed: []int64{1616887800000, 0},
+			wantTitle: "The Best Web Release Notes 2.0.390 - 2021-03-27",
+			wantTime:  "2021-03-27T23:30:00Z",
+		},
+		{name: "stage not started", stage: "package", wantErr: "the jobs of the stage package of iotic-node-package/390 have not started"},
+		{name: "unknown stage", stage: "deploy-prod", wantErr: "the stage deploy-prod of iotic-node-package/390 has not run"},
+		{name: "invalid timezone", timezone: "Europe/Nowhere", wantErr: "invalid timezone Europe/Nowhere"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Timezone = tt.timezone
+			cfg.ReleaseDateStage = tt.stage
+			cfg.Client = &mocks.MockClient{
+				DoFunc: func(req *http.Request) (*http.Response, error) {
+					if req.URL.Path != "/go/api/stages/iotic-node-package/390/package/2" {
+						t.Errorf("unexpected path: %s", req.URL.Path)
+					}
+					content, _ := json.Marshal(newStageInstance(t, tt.completed...))
+					return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(content))}, nil
+				},
+			}
+
+			timestamp, err := getReleaseTimestamp(cfg, "iotic-node-package", 390, history)
+			if !ErrorContains(err, tt.wantErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if got := timestamp.Format(time.RFC3339); got != tt.wantTime {
+				t.Errorf("expected: %s, got: %s", tt.wantTime, got)
+			}
+			release := newTestRelease()
+			release.Timestamp = timestamp
+			title, err := createConfluenceTitle(ConfluenceTemplate{}, newReleaseData(release))
+			if err != nil || title != tt.wantTitle {
+				t.Errorf("expected: %s, got: %s %v", tt.wantTitle, title, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// requireStageInterval is the time between the checks of a running stage, see RequireStageTimeout
+var requireStageInterval = 15 * time.Second
+
+// StageReport is the state of the required stage, it's the body of the 409 response
+type StageReport struct {
+	Pipeline string
+	Counter  int
+	Stage    string
+	Result   string
+	Status   string
+	Reason   string
+}
+
+// getStageReport returns the state of the stage of the pipeline run and whether it's still to finish
+func getStageReport(history *GocdPipelineHistory, pipeline string, counter int, stage string) (*StageReport, bool) {
+	report := &StageReport{Pipeline: pipeline, Counter: counter, Stage: stage}
+	for _, s := range history.Stages {
+		if !strings.EqualFold(s.Name, stage) {
+			continue
+		}
+		report.Result, report.Status = s.Result, s.Status
+		if !s.Scheduled {
+			report.Reason = fmt.Sprintf("the stage %s has not run yet", stage)
+			return report, true
+		}
+		// NOTE: GoCD reports the result of a running stage as Unknown
+		if s.Result == "Unknown" || s.Status == "Building" {
+			report.Reason = fmt.Sprintf("the stage %s is still running", stage)
+			return report, true
+		}
+		if s.Result != "Passed" {
+			report.Reason = fmt.Sprintf("the stage %s has not passed: %s", stage, s.Result)
+		}
+		return report, false
+	}
+	// NOTE: the later stages are only listed once they are scheduled
+	report.Reason = fmt.Sprintf("the stage %s has not run yet", stage)
+	return report, true
+}
+
+// checkRequiredStage fails with 409 unless the required stage of the run passed;
+// a running stage is checked again until RequireStageTimeout, it returns the latest history
+func checkRequiredStage(cfg *Config, pipeline string, counter int, history *GocdPipelineHistory) (*GocdPipelineHistory, error) {
+	if cfg.RequireStage == "" {
+		return history, nil
+	}
+	deadline := time.Now().Add(cfg.RequireStageTimeout)
+	for {
+		report, running := getStageReport(history, pipeline, counter, cfg.RequireStage)
+		if report.Reason == "" {
+			return history, nil
+		}
+		if !running || !time.Now().Add(requireStageInterval).Before(deadline) {
+			if running && cfg.RequireStageTimeout > 0 {
+				report.Reason = fmt.Sprintf("%s after %s", report.Reason, cfg.RequireStageTimeout)
+			}
+			return history, &StatusError{StatusCode: http.StatusConflict, Err: fmt.Errorf("%s", report.Reason), Body: report}
+		}
+
+		log.Printf("Waiting for the stage %s of %s/%d", cfg.RequireStage, pipeline, counter)
+		time.Sleep(requireStageInterval)
+		var err error
+		history, err = getGocdPipelineHistory(cfg, pipeline, counter)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+// readSampleHistory returns the sample run with the package stage in the result and status
+func readSampleHistory(t *testing.T, result string, status string) *GocdPipelineHistory {
+	content, err := os.ReadFile("./sample-data/gocd-pipeline-history.json")
+	if err != nil {
+		t.Fatalf("could not read file: %s", err)
+	}
+	history, err := parseGocdPipelineHistory(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history.Stages[0].Result = result
+	history.Stages[0].Status = status
+	return &history
+}
+
+func TestGetStageReport(t *testing.T) {
+	tests := []struct {
+		name        string
+		stage       string
+		result      string
+		status      string
+		wantReason  string
+		wantRunning bool
+	}{
+		{name: "passed", stage: "Package", result: "Passed", status: "Passed"},
+		{name: "failed", stage: "package", result: "Failed", status: "Failed", wantReason: "the stage package has not passed: Failed"},
+		{name: "cancelled", stage: "package", result: "Cancelled", status: "Cancelled", wantReason: "the stage package has not passed: Cancelled"},
+		{name: "running", stage: "package", result: "Unknown", status: "Building", wantReason: "the stage package is still running", wantRunning: true},
+		{name: "not scheduled", stage: "deploy-prod", result: "Passed", status: "Passed", wantReason: "the stage deploy-prod has not run yet", wantRunning: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := readSampleHistory(t, tt.result, tt.status)
+			report, running := getStageReport(history, "iotic-node-package", 390, tt.stage)
+			if report.Reason != tt.wantReason || running != tt.wantRunning {
+				t.Errorf("expected: %q %v, got: %q %v", tt.wantReason, tt.wantRunning, report.Reason, running)
+			}
+		})
+	}
+}
+
+func TestCheckRequiredStage(t *testing.T) {
+	interval := requireStageInterval
+	requireStageInterval = time.Millisecond
+	defer func() { requireStageInterval = interval }()
+
+	newConfig := func(timeout time.Duration, histories ...*GocdPipelineHistory) (*Config, *int) {
+		calls := 0
+		cfg := NewDefaultConfig()
+		cfg.RequireStage = "package"
+		cfg.RequireStageTimeout = timeout
+		cfg.Client = &mocks.MockClient{
+			DoFunc: func(req *http.Request) (*http.Response, error) {
+				// NOTE: the last history is repeated
+				history := histories[len(histories)-1]
+				if calls < len(histories) {
+					history = histories[calls]
+				}
+				calls++
+				content, _ := json.Marshal(history)
+				return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(content))}, nil
+			},
+		}
+		return cfg, &calls
+	}
+
+	t.Run("failed", func(t *testing.T) {
+		cfg, _ := newConfig(0)
+		_, err := checkRequiredStage(cfg, "iotic-node-package", 390, readSampleHistory(t, "Failed", "Failed"))
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusConflict {
+			t.Fatalf("expected 409, got: %v", err)
+		}
+		if report := statusErr.Body.(*StageReport); report.Result != "Failed" || report.Stage != "package" {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+
+	t.Run("running without waiting", func(t *testing.T) {
+		cfg, calls := newConfig(0)
+		_, err := checkRequiredStage(cfg, "iotic-node-package", 390, readSampleHistory(t, "Unknown", "Building"))
+		if !ErrorContains(err, "the stage package is still running") || *calls != 0 {
+			t.Errorf("unexpected error: %v %d", err, *calls)
+		}
+	})
+
+	t.Run("waits until passed", func(t *testing.T) {
+		cfg, calls := newConfig(time.Second, readSampleHistory(t, "Unknown", "Building"), readSampleHistory(t, "Passed", "Passed"))
+		history, err := checkRequiredStage(cfg, "iotic-node-package", 390, readSampleHistory(t, "Unknown", "Building"))
+		if err != nil || *calls != 2 || history.Stages[0].Result != "Passed" {
+			t.Errorf("unexpected result: %v %d", err, *calls)
+		}
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		running := readSampleHistory(t, "Unknown", "Building")
+		cfg, _ := newConfig(20*time.Millisecond, running)
+		_, err := checkRequiredStage(cfg, "iotic-node-package", 390, running)
+		if !ErrorContains(err, "the stage package is still running after 20ms") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -11,13 +12,30 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-var logger *log.Entry
-var requestID string
+// logger logs outside of the requests, e.g. when parsing the responses;
+// each request logs with its own logger, see newRequestLogger
+var logger = log.NewEntry(log.StandardLogger())
 
 type QueryParams struct {
 	Title    string
 	Pipeline string
 	Counter  int
+	// Label (e.g. 1.42.0) or Alias (latest or latest-passed) are resolved to the counter, see resolveCounter
+	Label string `json:",omitempty"`
+	Alias string `json:",omitempty"`
+	// Approved releases despite a failed gate
+	Approved bool
+	// DryRun only reports the Jira transitions, as jiraTransitionsDryRun does
+	DryRun bool
+	// onStage reports the progress of a job, see JobQueue
+	onStage func(name string)
+}
+
+// stage reports the start of a stage of createReleaseNotes, if anyone listens
+func (q *QueryParams) stage(name string) {
+	if q.onStage != nil {
+		q.onStage(name)
+	}
 }
 
 type Notes struct {
@@ -25,38 +43,103 @@ type Notes struct {
 	Groups            map[string][]string
 }
 
+// ReleaseResult is the response of the service;
+// it embeds Notes so that the release notes stay at the top level
+type ReleaseResult struct {
+	*Notes
+	Transitions *TransitionReport `json:",omitempty"`
+	// Update is the change summary when an existing post was regenerated
+	Update *ConfluenceUpdate `json:",omitempty"`
+	Gate   *GateReport       `json:",omitempty"`
+	// Redactions report what was stripped from the release notes of each rule set
+	Redactions []RedactionReport `json:",omitempty"`
+	// Variants are the release notes of each audience, see VariantConfig
+	Variants map[string]*VariantResult `json:",omitempty"`
+	// Locales report the missing translations and the post of each language, see LocaleConfig
+	Locales []*LocaleReport `json:",omitempty"`
+	// Counter is the run the label or alias of the query was resolved to
+	Counter int `json:",omitempty"`
+}
+
+// StatusError is an error with the status code (and an optional JSON body) of the response
+type StatusError struct {
+	StatusCode int
+	Err        error
+	Body       interface{}
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
 type Group struct {
 	Name         string
 	BulletPoints []string
 }
 
-func init() {
-	requestID = fmt.Sprintf("%v", xid.New())
+// newRequestLogger returns a logger with a new request ID
+func newRequestLogger() *log.Entry {
+	return log.WithFields(log.Fields{"requestID": xid.New().String()})
 }
 
 // Serve starts a local server on a configured port
 func Serve() {
 	cfg := NewDefaultConfig()
 
+	jobs, err := newJobQueue(cfg, createReleaseNotes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	jobs.start()
+
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		handleRequest(w, r, cfg)
 	})
+	http.HandleFunc("/validate", func(w http.ResponseWriter, r *http.Request) {
+		handleValidateRequest(w, r, cfg)
+	})
+	http.HandleFunc("/lint", func(w http.ResponseWriter, r *http.Request) {
+		handleLintRequest(w, r, cfg)
+	})
+	http.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		handleJobsRequest(w, r, jobs)
+	})
+	http.HandleFunc("/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		handleJobRequest(w, r, jobs)
+	})
+	receiver := newNotificationReceiver(cfg, jobs)
+	http.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		handleNotificationRequest(w, r, receiver)
+	})
 	log.Infof("starting server on %s", cfg.Port)
 	log.Fatal(http.ListenAndServe(cfg.Port, nil))
 }
 
-func writeResponseError(w http.ResponseWriter, err error) {
+func writeResponseError(w http.ResponseWriter, logger *log.Entry, err error) {
 	logger.Errorf("Got error %s", err.Error())
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		w.WriteHeader(statusErr.StatusCode)
+		if statusErr.Body != nil {
+			jsonBody, _ := json.Marshal(statusErr.Body)
+			w.Write(jsonBody)
+			return
+		}
+		w.Write([]byte(err.Error()))
+		return
+	}
 	w.WriteHeader(http.StatusBadRequest)
 	w.Write([]byte(fmt.Sprintf("%v", string(err.Error()))))
 }
 
-func getQueryParamsFromRequest(query url.Values) (*QueryParams, error) {
+func getQueryParamsFromRequest(logger *log.Entry, query url.Values) (*QueryParams, error) {
 
+	// NOTE: the title can also be set in the config, see createReleaseNotes
 	title := query.Get("title")
-	if title == "" {
-		return nil, fmt.Errorf("set title in query string")
-	}
 	logger.Infof("Title: %s\n", title)
 
 	pipeline := query.Get("pipeline")
@@ -65,7 +148,25 @@ func getQueryParamsFromRequest(query url.Values) (*QueryParams, error) {
 	}
 	logger.Infof("Pipeline: %s\n", pipeline)
 
+	params := &QueryParams{
+		Title:    title,
+		Pipeline: pipeline,
+		Label:    query.Get("label"),
+		Approved: query.Get("approved") == "true",
+		DryRun:   query.Get("dryRun") == "true",
+	}
+
+	// NOTE: the label and the aliases are resolved later, see resolveCounter
 	counterParam := query.Get("counter")
+	if isCounterAlias(counterParam) {
+		params.Alias = counterParam
+		logger.Infof("Counter: %s\n", counterParam)
+		return params, nil
+	}
+	if counterParam == "" && params.Label != "" {
+		logger.Infof("Label: %s\n", params.Label)
+		return params, nil
+	}
 	counter, err := strconv.Atoi(counterParam)
 	if err != nil {
 		return nil, fmt.Errorf("could not process counter")
@@ -74,16 +175,12 @@ func getQueryParamsFromRequest(query url.Values) (*QueryParams, error) {
 		return nil, fmt.Errorf("set counter in query string")
 	}
 	logger.Infof("Counter: %d\n", counter)
-	params := &QueryParams{
-		Title:    title,
-		Pipeline: pipeline,
-		Counter:  counter,
-	}
+	params.Counter = counter
 	return params, nil
 }
 
 func handleRequest(w http.ResponseWriter, r *http.Request, cfg *Config) {
-	logger = log.WithFields(log.Fields{"requestID": requestID})
+	logger := newRequestLogger()
 
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusNotImplemented)
@@ -92,58 +189,297 @@ func handleRequest(w http.ResponseWriter, r *http.Request, cfg *Config) {
 
 	logger.Infoln("Creating release notes")
 
-	queryParams, err := getQueryParamsFromRequest(r.URL.Query())
+	queryParams, err := getQueryParamsFromRequest(logger, r.URL.Query())
 	if err != nil {
-		writeResponseError(w, err)
+		writeResponseError(w, logger, err)
 		return
 	}
 
-	notes, err := createReleaseNotes(cfg, queryParams)
+	result, err := createReleaseNotes(cfg, queryParams)
 	if err != nil {
-		writeResponseError(w, err)
+		writeResponseError(w, logger, err)
 		return
 	}
-	if notes == nil {
+	if result == nil {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	jsonNotes, _ := json.Marshal(notes)
+	jsonNotes, _ := json.Marshal(result)
 	w.Write(jsonNotes)
 	w.WriteHeader(http.StatusOK)
 }
 
-func createReleaseNotes(cfg *Config, queryParams *QueryParams) (*Notes, error) {
+func createReleaseNotes(cfg *Config, queryParams *QueryParams) (*ReleaseResult, error) {
 
+	cfg = cfg.forPipeline(queryParams.Pipeline)
+	err := resolveCounter(cfg, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	title := queryParams.Title
+	if title == "" {
+		title = cfg.Title
+	}
+	if title == "" {
+		return nil, fmt.Errorf("set title in query string or in the pipeline config")
+	}
+
+	queryParams.stage(stageGocd)
 	pipelineHistory, err := getGocdPipelineHistory(cfg, queryParams.Pipeline, queryParams.Counter)
 	if err != nil {
 		return nil, err
 	}
+	pipelineHistory, err = checkRequiredStage(cfg, queryParams.Pipeline, queryParams.Counter, pipelineHistory)
+	if err != nil {
+		return nil, err
+	}
 
 	pipelineComparison, err := getGocdPipelineComparison(cfg, queryParams.Pipeline, queryParams.Counter)
 	if err != nil {
 		return nil, err
 	}
 
+	queryParams.stage(stageJira)
 	allJiraKeys := getStoriesFromCommits(pipelineComparison)
 	jiraIssues, err := getUniqueJiraIssues(cfg, allJiraKeys)
-	if err != nil || len(jiraIssues) == 0 {
-		// no JIRA issues found, so no release notes
+	if err != nil {
 		return nil, err
 	}
 
-	releaseNotes := extractReleaseNotes(jiraIssues)
-	if releaseNotes == nil || len(releaseNotes.Groups) == 0 {
+	var gate *GateReport
+	if cfg.Gate.Enforce {
+		queryParams.stage(stageGate)
+		gate = validateRelease(cfg, pipelineComparison, jiraIssues)
+		err = checkReleaseGate(gate, queryParams.Approved)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// NOTE: the variants can have release notes without the issues having any, e.g. the summaries or the authors
+	if len(jiraIssues) == 0 && len(cfg.Variants) == 0 {
+		// no JIRA issues found, so no release notes
+		return nil, nil
+	}
+
+	releaseNotes := extractReleaseNotes(jiraIssues, cfg.JiraReleaseNotesField)
+	hasNotes := releaseNotes != nil && len(releaseNotes.Groups) > 0
+	if !hasNotes && len(cfg.Variants) == 0 {
 		// JIRA issues found, but none have release notes
 		return nil, nil
 	}
+	result := &ReleaseResult{Notes: releaseNotes, Gate: gate}
+	if queryParams.Label != "" || queryParams.Alias != "" {
+		result.Counter = queryParams.Counter
+	}
 
 	version := pipelineHistory.Label
-	timestamp := convertGocdTimestampToGo(pipelineHistory.ScheduledDate)
-	_, err = publishReleaseNotesToConfluence(cfg, timestamp, queryParams.Title, queryParams.Pipeline, version, releaseNotes)
+	timestamp, err := getReleaseTimestamp(cfg, queryParams.Pipeline, queryParams.Counter, pipelineHistory)
 	if err != nil {
-		return releaseNotes, err
+		return result, err
+	}
+	release := &Release{
+		Title:      title,
+		Pipeline:   queryParams.Pipeline,
+		Counter:    queryParams.Counter,
+		Version:    version,
+		Timestamp:  timestamp,
+		Notes:      releaseNotes,
+		Issues:     jiraIssues,
+		Comparison: pipelineComparison,
+	}
+	variants := []*Release{}
+	for _, v := range cfg.Variants {
+		variant := newVariantRelease(cfg, v, release)
+		if len(variant.Notes.Groups) > 0 {
+			hasNotes = true
+		}
+		variants = append(variants, variant)
+	}
+	if !hasNotes {
+		// no release notes for any audience
+		return nil, nil
 	}
 
-	return releaseNotes, nil
+	queryParams.stage(stagePublish)
+	var locales []*LocaleRelease
+	if len(cfg.Locales) > 0 && cfg.publishesTo("confluence") && len(releaseNotes.Groups) > 0 {
+		locales, result.Locales, err = newLocaleReleases(cfg, release)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if len(cfg.Variants) == 0 {
+		result.Update, result.Redactions, err = publishRelease(cfg, release)
+		if err != nil {
+			return result, err
+		}
+	} else {
+		result.Variants = map[string]*VariantResult{}
+	}
+	for i, v := range cfg.Variants {
+		variant := variants[i]
+		variantResult := &VariantResult{Notes: variant.Notes}
+		result.Variants[v.Name] = variantResult
+		variantCfg, ok := cfg.forVariant(v)
+		if len(variant.Notes.Groups) == 0 || !ok {
+			// nothing to publish for this audience
+			continue
+		}
+		variantResult.Update, variantResult.Redactions, err = publishRelease(variantCfg, variant)
+		if variant.Post != nil {
+			variantResult.URL = variant.Post.URL()
+			if release.Post == nil {
+				// NOTE: the Jira comments link to the first variant published to Confluence
+				release.Post = variant.Post
+			}
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to publish variant %s: %w", v.Name, err)
+		}
+	}
+
+	err = publishLocales(cfg, locales)
+	if err != nil {
+		return result, err
+	}
+	if len(cfg.Variants) == 0 {
+		err = linkLocales(cfg, release, locales)
+	} else {
+		// NOTE: the variants have their own posts, so only the languages are linked
+		err = linkLocales(cfg, nil, locales)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if cfg.JiraCommentOnRelease || len(cfg.JiraTransitions) > 0 {
+		queryParams.stage(stageUpdate)
+	}
+	if cfg.JiraCommentOnRelease {
+		if release.Post == nil {
+			return result, fmt.Errorf("cannot comment on Jira issues - the release notes are not published to Confluence")
+		}
+		link := release.Post.URL()
+		if link == "" {
+			return result, fmt.Errorf("cannot comment on Jira issues - missing link to blog post %s", release.Post.ID)
+		}
+		comment := createReleaseComment(title, version, timestamp, link)
+		err = commentOnJiraIssues(cfg, jiraIssues, comment, link)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if len(cfg.JiraTransitions) > 0 {
+		result.Transitions = transitionJiraIssues(cfg, jiraIssues, cfg.JiraTransitionsDryRun || queryParams.DryRun)
+	}
+
+	return result, nil
+}
+
+// publishRelease publishes the release to Confluence and the other publishers,
+// the release notes are redacted for each publisher first
+func publishRelease(cfg *Config, release *Release) (*ConfluenceUpdate, []RedactionReport, error) {
+	redactor, err := newRedactor(cfg.Redaction, release)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var update *ConfluenceUpdate
+	if cfg.publishesTo("confluence") {
+		release.Post, update, err = publishReleaseNotesToConfluence(cfg, redactor.releaseFor("confluence"))
+		if err != nil {
+			return update, redactor.Reports, err
+		}
+	}
+
+	for _, publisher := range getPublishers(cfg) {
+		err = publisher.Publish(cfg, redactor.releaseFor(publisher.Name()))
+		if err != nil {
+			return update, redactor.Reports, fmt.Errorf("failed to publish to %s: %w", publisher.Name(), err)
+		}
+	}
+	return update, redactor.Reports, nil
+}
+
+func handleValidateRequest(w http.ResponseWriter, r *http.Request, cfg *Config) {
+	logger := newRequestLogger()
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	logger.Infoln("Validating release notes")
+
+	queryParams, err := getQueryParamsFromRequest(logger, r.URL.Query())
+	if err != nil {
+		writeResponseError(w, logger, err)
+		return
+	}
+
+	report, err := validateReleaseNotes(cfg, queryParams)
+	if err != nil {
+		writeResponseError(w, logger, err)
+		return
+	}
+
+	jsonReport, _ := json.Marshal(report)
+	if !report.Passed {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	w.Write(jsonReport)
+}
+
+// validateReleaseNotes runs the gate without publishing anything
+func validateReleaseNotes(cfg *Config, queryParams *QueryParams) (*GateReport, error) {
+	cfg = cfg.forPipeline(queryParams.Pipeline)
+	err := resolveCounter(cfg, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineComparison, err := getGocdPipelineComparison(cfg, queryParams.Pipeline, queryParams.Counter)
+	if err != nil {
+		return nil, err
+	}
+
+	allJiraKeys := getStoriesFromCommits(pipelineComparison)
+	jiraIssues, err := getUniqueJiraIssues(cfg, allJiraKeys)
+	if err != nil {
+		return nil, err
+	}
+	return validateRelease(cfg, pipelineComparison, jiraIssues), nil
+}
+
+func handleLintRequest(w http.ResponseWriter, r *http.Request, cfg *Config) {
+	logger := newRequestLogger()
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	jql := r.URL.Query().Get("jql")
+	if key == "" && jql == "" {
+		writeResponseError(w, logger, fmt.Errorf("set key or jql in query string"))
+		return
+	}
+
+	report, err := lintJiraIssues(cfg, key, jql)
+	if err != nil {
+		writeResponseError(w, logger, err)
+		return
+	}
+
+	jsonReport, _ := json.Marshal(report)
+	if !report.Passed {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	w.Write(jsonReport)
 }
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SiteConfig keeps a static site with the release notes of all pipelines:
+// <dir>/index.html, <dir>/<pipeline>/index.html, <dir>/<pipeline>/<version>.html
+// and an Atom feed <dir>/<pipeline>/feed.xml
+type SiteConfig struct {
+	Dir string
+	// BaseURL is where the site is served from, used for absolute links in the feeds
+	BaseURL string
+	// TemplateDir overrides the default templates (templates/site) with templates of the same name
+	TemplateDir string
+}
+
+//go:embed templates/site/*.html
+var siteTemplates embed.FS
+
+// SitePipeline is the data of the pipeline index page
+type SitePipeline struct {
+	Title    string
+	Path     string
+	Latest   *ReleaseData
+	Releases []*ReleaseData
+}
+
+// SitePublisher stores each release in the site directory
+// and then rebuilds the whole site from all the stored releases
+type SitePublisher struct{}
+
+func (p *SitePublisher) Name() string {
+	return "site"
+}
+
+// siteMutex serialises the publishing, the jobs run concurrently and each rebuilds the whole site
+var siteMutex sync.Mutex
+
+func (p *SitePublisher) Publish(cfg *Config, release *Release) error {
+	data := newReleaseData(release)
+	// NOTE: the site (including the data directory) is public,
+	// so don't link to Confluence or keep the Jira issues, the commit authors and the materials
+	data.URL = ""
+	data.Issues = nil
+	data.Authors = nil
+	data.Materials = nil
+	siteMutex.Lock()
+	defer siteMutex.Unlock()
+	err := saveSiteRelease(cfg.Site.Dir, data)
+	if err != nil {
+		return err
+	}
+	return buildSite(cfg.Site)
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func sitePath(name string) string {
+	return unsafePathChars.ReplaceAllString(name, "-")
+}
+
+func saveSiteRelease(dir string, data *ReleaseData) error {
+	dataDir := filepath.Join(dir, "data", sitePath(data.Pipeline))
+	err := os.MkdirAll(dataDir, 0755)
+	if err != nil {
+		return err
+	}
+	jsonStr, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dataDir, sitePath(data.Version)+".json"), jsonStr, 0644)
+}
+
+// loadSitePipelines reads the release history, the newest releases first
+func loadSitePipelines(dir string) ([]*SitePipeline, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "data", "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	pipelines := map[string]*SitePipeline{}
+	for _, f := range files {
+		input, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var data ReleaseData
+		err = json.Unmarshal(input, &data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		path := sitePath(data.Pipeline)
+		if _, ok := pipelines[path]; !ok {
+			pipelines[path] = &SitePipeline{Path: path}
+		}
+		pipelines[path].Releases = append(pipelines[path].Releases, &data)
+	}
+
+	result := []*SitePipeline{}
+	for _, p := range pipelines {
+		sort.Slice(p.Releases, func(i, j int) bool {
+			return p.Releases[i].Timestamp.After(p.Releases[j].Timestamp)
+		})
+		p.Latest = p.Releases[0]
+		p.Title = p.Latest.Title
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Title < result[j].Title
+	})
+	return result, nil
+}
+
+func parseSiteTemplates(templateDir string) (*template.Template, error) {
+	tmpl, err := template.New("site").Funcs(htmlTemplateFuncs).Funcs(template.FuncMap{
+		"releasePath": func(version string) string { return sitePath(version) + ".html" },
+	}).ParseFS(siteTemplates, "templates/site/*.html")
+	if err != nil {
+		return nil, err
+	}
+	if templateDir == "" {
+		return tmpl, nil
+	}
+	// NOTE: templates with the same name replace the default ones
+	return tmpl.ParseGlob(filepath.Join(templateDir, "*.html"))
+}
+
+func buildSite(site SiteConfig) error {
+	tmpl, err := parseSiteTemplates(site.TemplateDir)
+	if err != nil {
+		return err
+	}
+	pipelines, err := loadSitePipelines(site.Dir)
+	if err != nil {
+		return err
+	}
+
+	err = writeSiteFile(tmpl, "index.html", filepath.Join(site.Dir, "index.html"), map[string]interface{}{"Pipelines": pipelines})
+	if err != nil {
+		return err
+	}
+	for _, p := range pipelines {
+		pipelineDir := filepath.Join(site.Dir, p.Path)
+		err = os.MkdirAll(pipelineDir, 0755)
+		if err != nil {
+			return err
+		}
+		err = writeSiteFile(tmpl, "pipeline.html", filepath.Join(pipelineDir, "index.html"), p)
+		if err != nil {
+			return err
+		}
+		for _, r := range p.Releases {
+			err = writeSiteFile(tmpl, "release.html", filepath.Join(pipelineDir, sitePath(r.Version)+".html"), r)
+			if err != nil {
+				return err
+			}
+		}
+		feed, err := createAtomFeed(site.BaseURL, p)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(pipelineDir, "feed.xml"), feed, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("Built site %s", site.Dir)
+	return nil
+}
+
+func writeSiteFile(tmpl *template.Template, name string, filename string, data interface{}) error {
+	var buf bytes.Buffer
+	err := tmpl.ExecuteTemplate(&buf, name, data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// AtomFeed represents an Atom feed, see https://datatracker.ietf.org/doc/html/rfc4287
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    AtomLink    `xml:"link"`
+	Entries []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    AtomLink    `xml:"link"`
+	Content AtomContent `xml:"content"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type AtomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func createAtomFeed(baseURL string, p *SitePipeline) ([]byte, error) {
+	pipelineURL := strings.TrimRight(baseURL, "/") + "/" + p.Path + "/"
+	feed := &AtomFeed{
+		Title:   fmt.Sprintf("%s Release Notes", p.Title),
+		ID:      pipelineURL,
+		Updated: p.Latest.Timestamp.Format(time.RFC3339),
+		Link:    AtomLink{Href: pipelineURL},
+	}
+	for _, r := range p.Releases {
+		var content bytes.Buffer
+		for _, g := range r.Groups {
+			content.WriteString("<h2>" + template.HTMLEscapeString(g.Name) + "</h2>")
+			content.WriteString(jiraLinesToHTML(g.BulletPoints))
+		}
+		releaseURL := pipelineURL + sitePath(r.Version) + ".html"
+		feed.Entries = append(feed.Entries, AtomEntry{
+			Title:   fmt.Sprintf("%s %s", r.Title, r.Version),
+			ID:      releaseURL,
+			Updated: r.Timestamp.Format(time.RFC3339),
+			Link:    AtomLink{Href: releaseURL},
+			Content: AtomContent{Type: "html", Value: content.String()},
+		})
+	}
+	output, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), output...), nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSitePublisher(t *testing.T) {
+	dir := t.TempDir()
+	cfg := NewDefaultConfig()
+	cfg.Site = SiteConfig{Dir: dir, BaseURL: "https://docs.example.com/release-notes/"}
+
+	older := newTestRelease()
+	older.Version = "2.0.389"
+	older.Timestamp = older.Timestamp.Add(-24 * time.Hour)
+	for _, release := range []*Release{newTestRelease(), older} {
+		err := (&SitePublisher{}).Publish(cfg, release)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, f := range []string{"index.html", "iotic-webbing/index.html", "iotic-webbing/2.0.389.html", "iotic-webbing/2.0.390.html"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Errorf("missing page: %v", err)
+		}
+	}
+
+	release, _ := ioutil.ReadFile(filepath.Join(dir, "iotic-webbing", "2.0.390.html"))
+	if !strings.Contains(string(release), `<li><a href="https://example.com/rpc">RPCStatus</a></li>`) {
+		t.Errorf("unexpected release page:\n%s", release)
+	}
+	if strings.Contains(string(release), "https://example.com/wiki") {
+		t.Errorf("the release page must not link to Confluence:\n%s", release)
+	}
+
+	index, _ := ioutil.ReadFile(filepath.Join(dir, "iotic-webbing", "index.html"))
+	if strings.Index(string(index), "2.0.390.html") > strings.Index(string(index), "2.0.389.html") {
+		t.Errorf("expected the newest release first:\n%s", index)
+	}
+
+	var feed AtomFeed
+	input, _ := ioutil.ReadFile(filepath.Join(dir, "iotic-webbing", "feed.xml"))
+	err := xml.Unmarshal(input, &feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Entries) != 2 || feed.Entries[0].ID != "https://docs.example.com/release-notes/iotic-webbing/2.0.390.html" {
+		t.Errorf("unexpected feed: %+v", feed)
+	}
+}
+
+func TestSitePublisherKeepsTheCommitsPrivate(t *testing.T) {
+	dir := t.TempDir()
+	cfg := NewDefaultConfig()
+	cfg.Site = SiteConfig{Dir: dir}
+	release := newTestRelease()
+	release.Comparison = readSampleComparison(t, "./sample-data/gocd-pipeline-compare-short.json")
+
+	err := (&SitePublisher{}).Publish(cfg, release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, _ := ioutil.ReadFile(path)
+		for _, private := range []string{"this-developer@users.noreply.github.com", "git@github.com:Iotic-Labs/system-core.git", "ef66f41856582bc700ef7d08878882315afc9359", "Added host identifiers"} {
+			if strings.Contains(string(content), private) {
+				t.Errorf("%s must not contain %s", path, private)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSitePublisherConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	cfg := NewDefaultConfig()
+	cfg.Site = SiteConfig{Dir: dir}
+
+	versions := []string{}
+	for i := 0; i < 20; i++ {
+		versions = append(versions, fmt.Sprintf("2.0.%d", 380+i))
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, len(versions))
+	for i, version := range versions {
+		release := newTestRelease()
+		release.Version = version
+		release.Timestamp = release.Timestamp.Add(time.Duration(i) * time.Hour)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- (&SitePublisher{}).Publish(cfg, release)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	index, _ := ioutil.ReadFile(filepath.Join(dir, "iotic-webbing", "index.html"))
+	for _, version := range versions {
+		if !strings.Contains(string(index), version+".html") {
+			t.Errorf("missing release %s:\n%s", version, index)
+		}
+	}
+}
+
+func TestSiteTemplatesCanBeOverridden(t *testing.T) {
+	dir := t.TempDir()
+	templateDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(templateDir, "release.html"), []byte(`{{ define "release.html" }}custom {{ .Version }}{{ end }}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewDefaultConfig()
+	cfg.Site = SiteConfig{Dir: dir, TemplateDir: templateDir}
+
+	err = (&SitePublisher{}).Publish(cfg, newTestRelease())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release, _ := ioutil.ReadFile(filepath.Join(dir, "iotic-webbing", "2.0.390.html"))
+	if string(release) != "custom 2.0.390" {
+		t.Errorf("unexpected release page: %s", release)
+	}
+}
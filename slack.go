@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Slack limits, see https://api.slack.com/reference/block-kit/blocks
+const (
+	slackMaxBlocks      = 50
+	slackMaxHeaderText  = 150
+	slackMaxSectionText = 3000
+)
+
+// SlackConfig routes the release notes of pipelines to Slack incoming webhooks
+type SlackConfig struct {
+	Routes []SlackRoute
+}
+
+// SlackRoute sends the release notes of a pipeline ("*" for all pipelines)
+// to one or more incoming webhooks, i.e. channels
+type SlackRoute struct {
+	Pipeline string
+	Webhooks []string
+}
+
+// SlackMessage represents a Slack message using Block Kit
+type SlackMessage struct {
+	// Text is used in notifications and as a fallback
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks"`
+}
+
+type SlackBlock struct {
+	Type     string      `json:"type"`
+	Text     *SlackText  `json:"text,omitempty"`
+	Elements []SlackText `json:"elements,omitempty"`
+}
+
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackPublisher sends a condensed version of the release notes to Slack
+type SlackPublisher struct{}
+
+func (p *SlackPublisher) Name() string {
+	return "slack"
+}
+
+func (p *SlackPublisher) Publish(cfg *Config, release *Release) error {
+	webhooks := getSlackWebhooks(cfg.Slack, release.Pipeline)
+	if len(webhooks) == 0 {
+		return nil
+	}
+	message := createSlackMessage(release)
+	for _, webhook := range webhooks {
+		err := postSlackMessage(cfg, webhook, message)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getSlackWebhooks(slack SlackConfig, pipeline string) []string {
+	webhooks := []string{}
+	for _, route := range slack.Routes {
+		if matchPipeline(route.Pipeline, pipeline) {
+			webhooks = append(webhooks, route.Webhooks...)
+		}
+	}
+	return unique(webhooks)
+}
+
+func createSlackMessage(release *Release) *SlackMessage {
+	title := fmt.Sprintf("%s %s", release.Title, release.Version)
+	message := &SlackMessage{
+		Text: fmt.Sprintf("%s released", title),
+		Blocks: []SlackBlock{
+			{Type: "header", Text: &SlackText{Type: "plain_text", Text: truncate(title, slackMaxHeaderText)}},
+		},
+	}
+
+	var link *SlackBlock
+	if release.Post != nil && release.Post.URL() != "" {
+		link = &SlackBlock{
+			Type: "context",
+			Elements: []SlackText{
+				{Type: "mrkdwn", Text: fmt.Sprintf("<%s|Read the full release notes in Confluence>", release.Post.URL())},
+			},
+		}
+	}
+
+	// keep space for the link and for the note about missing groups
+	maxGroups := slackMaxBlocks - len(message.Blocks) - 2
+	groups := sortedGroups(release.Notes)
+	for i, group := range groups {
+		if i == maxGroups {
+			message.Blocks = append(message.Blocks, SlackBlock{
+				Type: "section",
+				Text: &SlackText{Type: "mrkdwn", Text: fmt.Sprintf("_…and %d more groups_", len(groups)-maxGroups)},
+			})
+			break
+		}
+		lines := []string{fmt.Sprintf("*%s*", group.Name)}
+		for _, l := range group.BulletPoints {
+			lines = append(lines, jiraToSlack(l))
+		}
+		message.Blocks = append(message.Blocks, SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: "mrkdwn", Text: truncate(strings.Join(lines, "\n"), slackMaxSectionText)},
+		})
+	}
+
+	if link != nil {
+		message.Blocks = append(message.Blocks, *link)
+	}
+	return message
+}
+
+// truncate shortens the text to at most max characters
+func truncate(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:max-1]) + "…"
+}
+
+func postSlackMessage(cfg *Config, webhook string, message *SlackMessage) error {
+
+	// see https://api.slack.com/messaging/webhooks
+	// NOTE: the webhook URL is a secret, so it's not logged
+	log.Printf("Calling Slack webhook")
+
+	jsonStr, _ := json.Marshal(message)
+	req, err := http.NewRequest(http.MethodPost, webhook, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		response, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("failed to post to Slack: %d %s", resp.StatusCode, string(response))
+	}
+	return nil
+}
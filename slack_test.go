@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRelease() *Release {
+	post := &ConfluenceBlogPost{ID: "539492380"}
+	post.Links.Base = "https://example.com/wiki"
+	post.Links.WebUI = "/spaces/RN/blog/2021/03/26/539492380"
+	return &Release{
+		Title:     "The Best Web",
+		Pipeline:  "iotic-webbing",
+		Counter:   614,
+		Version:   "2.0.390",
+		Timestamp: time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC),
+		Notes: &Notes{
+			Groups: map[string][]string{
+				"Improvements": {"* Impr1", "* [RPCStatus|https://example.com/rpc]"},
+				"Bug Fixes":    {"* BF1"},
+			},
+		},
+		Post: post,
+	}
+}
+
+func TestSlackPublisher(t *testing.T) {
+	messages := []SlackMessage{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var message SlackMessage
+		err := json.Unmarshal(body, &message)
+		if err != nil {
+			t.Error(err)
+		}
+		messages = append(messages, message)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cfg := NewDefaultConfig()
+	cfg.Client = server.Client()
+	cfg.Slack = SlackConfig{
+		Routes: []SlackRoute{
+			{Pipeline: "iotic-webbing", Webhooks: []string{server.URL + "/web"}},
+			{Pipeline: "*", Webhooks: []string{server.URL + "/all"}},
+			{Pipeline: "another-pipeline", Webhooks: []string{server.URL + "/another"}},
+		},
+	}
+
+	err := (&SlackPublisher{}).Publish(cfg, newTestRelease())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got: %d", len(messages))
+	}
+
+	blocks := messages[0].Blocks
+	if len(blocks) != 4 {
+		t.Fatalf("expected header, 2 groups and a link, got: %+v", blocks)
+	}
+	if blocks[0].Text.Text != "The Best Web 2.0.390" {
+		t.Errorf("unexpected header: %v", blocks[0].Text.Text)
+	}
+	if blocks[1].Text.Text != "*Bug Fixes*\n• BF1" {
+		t.Errorf("unexpected section: %v", blocks[1].Text.Text)
+	}
+	if !strings.Contains(blocks[2].Text.Text, "• <https://example.com/rpc|RPCStatus>") {
+		t.Errorf("unexpected section: %v", blocks[2].Text.Text)
+	}
+	if !strings.Contains(blocks[3].Elements[0].Text, "https://example.com/wiki/spaces/RN/blog/2021/03/26/539492380") {
+		t.Errorf("unexpected link: %v", blocks[3].Elements[0].Text)
+	}
+}
+
+func TestCreateSlackMessageIsTruncated(t *testing.T) {
+	release := newTestRelease()
+	release.Title = strings.Repeat("a", 200)
+	release.Notes.Groups = map[string][]string{}
+	for i := 0; i < 60; i++ {
+		release.Notes.Groups[fmt.Sprintf("Group %02d", i)] = []string{strings.Repeat("b", 4000)}
+	}
+
+	message := createSlackMessage(release)
+
+	if len(message.Blocks) != slackMaxBlocks {
+		t.Errorf("expected %d blocks, got: %d", slackMaxBlocks, len(message.Blocks))
+	}
+	if len([]rune(message.Blocks[0].Text.Text)) != slackMaxHeaderText {
+		t.Errorf("header was not truncated: %d", len(message.Blocks[0].Text.Text))
+	}
+	for _, block := range message.Blocks[1:] {
+		if block.Text != nil && len([]rune(block.Text.Text)) > slackMaxSectionText {
+			t.Errorf("section was not truncated: %d", len(block.Text.Text))
+		}
+	}
+	more := message.Blocks[len(message.Blocks)-2].Text.Text
+	if more != "_…and 13 more groups_" {
+		t.Errorf("unexpected note about missing groups: %v", more)
+	}
+}
@@ -6,7 +6,8 @@ import (
 	"gopkg.in/go-playground/validator.v9"
 )
 
-var validate *validator.Validate
+// validate checks the parsed responses, it's safe for concurrent use, so it's created once
+var validate = validator.New()
 
 func isJSON(jsonData []byte) bool {
 	var j map[string]interface{}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/mail"
+	"sort"
+)
+
+// VariantConfig produces the release notes for an audience, e.g. internal or customer,
+// from the same GoCD and Jira data; each variant has its own publishers
+type VariantConfig struct {
+	Name string
+	// Publishers of the variant, e.g. [confluence, email], among the configured publishers; all of them when empty
+	Publishers []string
+	// Labels include only the Jira issues with one of the labels, e.g. [public]; all issues when empty
+	Labels []string
+	// Groups include only these groups, e.g. [Features, Bug Fixes]; all groups when empty
+	Groups []string
+	// FallbackSummaries add the summaries of the issues without release notes to the "Other Changes" group
+	FallbackSummaries bool
+	// Authors add the commit authors to the "Contributors" group
+	Authors bool
+	// ConfluenceSpaceKey and ConfluenceTitle (a template) override the Confluence post of the variant,
+	// so that the variants don't publish posts with the same title
+	ConfluenceSpaceKey string
+	ConfluenceTitle    string
+}
+
+// the groups added by the variants
+const (
+	fallbackGroup     = "Other Changes"
+	contributorsGroup = "Contributors"
+)
+
+// VariantResult is the part of the response of one variant
+type VariantResult struct {
+	*Notes
+	URL        string            `json:",omitempty"`
+	Update     *ConfluenceUpdate `json:",omitempty"`
+	Redactions []RedactionReport `json:",omitempty"`
+}
+
+// forVariant returns a copy of the config publishing the variant, the publishers of the variant
+// are limited to the publishers of the config (e.g. of the pipeline); false when none is left
+func (cfg *Config) forVariant(v VariantConfig) (*Config, bool) {
+	c := *cfg
+	if v.ConfluenceSpaceKey != "" {
+		c.ConfluenceSpaceKey = v.ConfluenceSpaceKey
+	}
+	if v.ConfluenceTitle != "" {
+		c.ConfluenceTitle = v.ConfluenceTitle
+	}
+	if len(v.Publishers) == 0 {
+		return &c, true
+	}
+	c.Publishers = []string{}
+	for _, name := range v.Publishers {
+		if cfg.publishesTo(name) {
+			c.Publishers = append(c.Publishers, name)
+		}
+	}
+	return &c, len(c.Publishers) > 0
+}
+
+// newVariantRelease returns a copy of the release with the issues and notes of the variant
+func newVariantRelease(cfg *Config, v VariantConfig, release *Release) *Release {
+	variant := *release
+	variant.Post = nil
+
+	variant.Issues = []JiraIssue{}
+	for _, issue := range release.Issues {
+		if len(v.Labels) == 0 || hasAnyLabel(issue, v.Labels) {
+			variant.Issues = append(variant.Issues, issue)
+		}
+	}
+
+	notes := extractReleaseNotes(variant.Issues, cfg.JiraReleaseNotesField)
+	notes.DependabotChanges = release.Notes.DependabotChanges
+	if len(v.Groups) > 0 {
+		for name := range notes.Groups {
+			if !containsStringFold(v.Groups, name) {
+				delete(notes.Groups, name)
+			}
+		}
+	}
+	if v.FallbackSummaries {
+		for _, issue := range variant.Issues {
+			if getJiraTextField(issue, cfg.JiraReleaseNotesField) == "" && issue.Fields.Summary != "" {
+				notes.Groups[fallbackGroup] = append(notes.Groups[fallbackGroup], fmt.Sprintf("* %s %s", issue.Key, issue.Fields.Summary))
+			}
+		}
+	}
+	if v.Authors {
+		authors, _ := getMaterialData(release.Comparison)
+		names := []string{}
+		for _, author := range authors {
+			// NOTE: only the names, e.g. "This Developer <12345678+this-developer@users.noreply.github.com>"
+			if address, err := mail.ParseAddress(author); err == nil && address.Name != "" {
+				author = address.Name
+			}
+			if !containsString(names, author) {
+				names = append(names, author)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			notes.Groups[contributorsGroup] = append(notes.Groups[contributorsGroup], "* "+name)
+		}
+	}
+	variant.Notes = notes
+	return &variant
+}
+
+func hasAnyLabel(issue JiraIssue, labels []string) bool {
+	for _, label := range issue.Fields.Labels {
+		if containsString(labels, label) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Iotic-Labs/gocd-jira-release-notes/mocks"
+)
+
+func newVariantTestIssue(key string, summary string, labels []string, notes string) JiraIssue {
+	issue := JiraIssue{Key: key}
+	issue.Fields.Summary = summary
+	issue.Fields.Labels = labels
+	issue.Fields.Customfield10110 = notes
+	return issue
+}
+
+func TestNewVariantRelease(t *testing.T) {
+	cfg := NewDefaultConfig()
+	release := newTestRelease()
+	release.Comparison = readSampleComparison(t, "./sample-data/gocd-pipeline-compare-short.json")
+	release.Issues = []JiraIssue{
+		newVariantTestIssue("JI-1", "Public feature", []string{"public"}, "h4. Features\n* Feature1\nh4. Internal\n* Restart the brokers"),
+		newVariantTestIssue("JI-2", "Internal fix", []string{}, "h4. Bug Fixes\n* BF1"),
+		newVariantTestIssue("JI-3", "Refactoring", []string{}, ""),
+	}
+
+	tests := []struct {
+		name    string
+		variant VariantConfig
+		want    map[string][]string
+	}{
+		{
+			name:    "customer",
+			variant: VariantConfig{Name: "customer", Labels: []string{"public"}, Groups: []string{"features", "Bug Fixes"}},
+			want:    map[string][]string{"Features": {"* Feature1"}},
+		},
+		{
+			name:    "internal",
+			variant: VariantConfig{Name: "internal", FallbackSummaries: true},
+			want: map[string][]string{
+				"Features":    {"* Feature1"},
+				"Internal":    {"* Restart the brokers"},
+				"Bug Fixes":   {"* BF1"},
+				fallbackGroup: {"* JI-3 Refactoring"},
+			},
+		},
+		{
+			name:    "no public issues",
+			variant: VariantConfig{Name: "customer", Labels: []string{"external"}},
+			want:    map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variant := newVariantRelease(cfg, tt.variant, release)
+			if !reflect.DeepEqual(tt.want, variant.Notes.Groups) {
+				t.Errorf("expected: %q, got: %q", tt.want, variant.Notes.Groups)
+			}
+			if variant.Post != nil {
+				t.Errorf("the variant should not have the post of the release")
+			}
+		})
+	}
+
+	if len(release.Issues) != 3 || release.Notes.Groups["Improvements"] == nil {
+		t.Errorf("the release should not be changed: %v", release.Notes.Groups)
+	}
+}
+
+func TestNewVariantReleaseAuthors(t *testing.T) {
+	release := newTestRelease()
+	release.Comparison = readSampleComparison(t, "./sample-data/gocd-pipeline-compare-short.json")
+	variant := newVariantRelease(NewDefaultConfig(), VariantConfig{Name: "internal", Authors: true}, release)
+
+	contributors := variant.Notes.Groups[contributorsGroup]
+	if len(contributors) == 0 {
+		t.Fatalf("expected the contributors, got: %q", variant.Notes.Groups)
+	}
+	for _, c := range contributors {
+		if strings.Contains(c, "@") {
+			t.Errorf("expected only the names of the authors, got: %s", c)
+		}
+	}
+}
+
+func TestForVariant(t *testing.T) {
+	tests := []struct {
+		name       string
+		publishers []string
+		variant    []string
+		want       []string
+		wantOK     bool
+	}{
+		{name: "variant among the pipeline publishers", publishers: []string{"confluence", "slack"}, variant: []string{"slack", "email"}, want: []string{"slack"}, wantOK: true},
+		{name: "no variant publishers", publishers: []string{"confluence", "slack"}, want: []string{"confluence", "slack"}, wantOK: true},
+		{name: "all publishers", variant: []string{"email"}, want: []string{"email"}, wantOK: true},
+		{name: "none left", publishers: []string{"confluence"}, variant: []string{"email"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Publishers = tt.publishers
+			variant, ok := cfg.forVariant(VariantConfig{Name: "customer", Publishers: tt.variant, ConfluenceTitle: "{{.Title}} for customers"})
+
+			if ok != tt.wantOK || (len(tt.want) > 0 || len(variant.Publishers) > 0) && !reflect.DeepEqual(variant.Publishers, tt.want) {
+				t.Errorf("expected: %v %v, got: %v %v", tt.want, tt.wantOK, variant.Publishers, ok)
+			}
+			if variant.ConfluenceTitle != "{{.Title}} for customers" || variant.ConfluenceSpaceKey != cfg.ConfluenceSpaceKey {
+				t.Errorf("unexpected Confluence overrides: %s %s", variant.ConfluenceTitle, variant.ConfluenceSpaceKey)
+			}
+			if !reflect.DeepEqual(cfg.Publishers, tt.publishers) {
+				t.Errorf("the config should not be changed: %v", cfg.Publishers)
+			}
+		})
+	}
+}
+
+func TestCreateReleaseNotesVariantWithoutReleaseNotes(t *testing.T) {
+	messages := 0
+	cfg := NewDefaultConfig()
+	cfg.Publishers = []string{"slack"}
+	cfg.Slack = SlackConfig{Routes: []SlackRoute{{Pipeline: "*", Webhooks: []string{"https://hooks.example.com/internal"}}}}
+	cfg.Variants = []VariantConfig{
+		{Name: "internal", FallbackSummaries: true},
+		{Name: "customer", Publishers: []string{"confluence"}},
+	}
+	cfg.Client = &mocks.MockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			var content []byte
+			switch {
+			case strings.HasSuffix(cfg.GocdUrl, req.Host):
+				content = readSampleGocdPipeline(t)
+			case strings.HasSuffix(cfg.JiraUrl, req.Host):
+				// NOTE: none of the issues has release notes
+				var issue map[string]interface{}
+				json.Unmarshal(readSampleJira(t, req.URL.Path), &issue)
+				issue["fields"].(map[string]interface{})["customfield_10110"] = nil
+				content, _ = json.Marshal(issue)
+			case req.Host == "hooks.example.com":
+				messages++
+				content = []byte("ok")
+			default:
+				t.Errorf("unexpected request: %s", req.URL)
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(content))}, nil
+		},
+	}
+
+	result, err := createReleaseNotes(cfg, &QueryParams{Title: "The Best Web", Pipeline: "iotic-webbing", Counter: 614})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || len(result.Variants["internal"].Groups[fallbackGroup]) == 0 || messages != 1 {
+		t.Fatalf("expected the internal variant to be published, got: %+v %d", result, messages)
+	}
+	if len(result.Variants["customer"].Groups) != 0 {
+		t.Errorf("expected no release notes for customers, got: %v", result.Variants["customer"].Groups)
+	}
+}
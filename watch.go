@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchConfig polls GoCD for new runs of the pipelines, for servers without a notification plugin
+type WatchConfig struct {
+	Pipelines []WatchPipeline
+	// Interval between the polls, 1m by default; it doubles up to MaxInterval (15m by default)
+	// while there are no new runs or GoCD fails, and resets when there is a new run
+	Interval    time.Duration
+	MaxInterval time.Duration
+	// StateFile keeps the runs processed for each pipeline, e.g. /var/lib/release-notes/watch.json
+	StateFile string
+}
+
+// WatchPipeline is a pipeline and its release stage, the release notes are created when the stage passes
+type WatchPipeline struct {
+	Pipeline string
+	Stage    string
+	// Title of the release notes, the title of the config by default
+	Title string
+}
+
+const (
+	defaultWatchInterval    = time.Minute
+	defaultWatchMaxInterval = 15 * time.Minute
+)
+
+// watchBacklog is how many runs behind the newest one a run can still pass and be processed
+var watchBacklog = 100
+
+// WatchState is what was processed and the ETag of the history of each pipeline
+type WatchState struct {
+	// Counters are the low-water marks, every run up to the counter is done
+	Counters map[string]int
+	// Done are the runs after the low-water mark which were processed or failed
+	Done map[string][]int
+	// Failed are the errors of the runs which can't be processed, e.g. stopped by a gate
+	Failed map[string]map[int]string
+	ETags  map[string]string
+}
+
+// Watcher creates the release notes of the new runs of the watched pipelines
+type Watcher struct {
+	cfg   *Config
+	run   func(*Config, *QueryParams) (*ReleaseResult, error)
+	state *WatchState
+	out   io.Writer
+}
+
+func newWatcher(cfg *Config, run func(*Config, *QueryParams) (*ReleaseResult, error), out io.Writer) (*Watcher, error) {
+	w := &Watcher{cfg: cfg, run: run, state: &WatchState{Counters: map[string]int{}, Done: map[string][]int{}, Failed: map[string]map[int]string{}, ETags: map[string]string{}}, out: out}
+	err := w.load()
+	return w, err
+}
+
+// watch polls until stop is closed
+func (w *Watcher) watch(stop <-chan struct{}) {
+	interval := w.interval(0, true, nil)
+	for {
+		found, err := w.poll()
+		if err != nil {
+			fmt.Fprintf(w.out, "poll failed: %v\n", err)
+		}
+		interval = w.interval(interval, found, err)
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// interval returns the next polling interval, backing off while there is nothing new
+func (w *Watcher) interval(current time.Duration, found bool, err error) time.Duration {
+	min := w.cfg.Watch.Interval
+	if min == 0 {
+		min = defaultWatchInterval
+	}
+	max := w.cfg.Watch.MaxInterval
+	if max == 0 {
+		max = defaultWatchMaxInterval
+	}
+	if (found && err == nil) || current == 0 {
+		return min
+	}
+	current *= 2
+	if current > max {
+		return max
+	}
+	return current
+}
+
+// poll checks every pipeline once and reports whether there was a new run
+func (w *Watcher) poll() (bool, error) {
+	found := false
+	failures := []string{}
+	for _, p := range w.cfg.Watch.Pipelines {
+		n, err := w.pollPipeline(p)
+		found = found || n > 0
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", p.Pipeline, err))
+		}
+	}
+	if len(failures) > 0 {
+		return found, fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return found, nil
+}
+
+// pollPipeline creates the release notes of the passed runs not processed yet, the oldest first, and returns how many;
+// a run which can't be processed is recorded as failed, other errors stop the poll and the run is retried on the next one
+func (w *Watcher) pollPipeline(p WatchPipeline) (int, error) {
+	page, etag, err := getGocdPipelineHistoryPage(w.cfg, p.Pipeline, w.state.ETags[p.Pipeline])
+	if err != nil || page == nil || len(page.Pipelines) == 0 {
+		return 0, err
+	}
+
+	mark, ok := w.state.Counters[p.Pipeline]
+	if !ok {
+		// NOTE: the first poll starts from the latest passed run, rather than publishing the whole history
+		mark = page.Pipelines[len(page.Pipelines)-1].Counter - 1
+		for _, run := range page.Pipelines {
+			if isStagePassed(run, p.Stage) {
+				mark = run.Counter
+				break
+			}
+		}
+		fmt.Fprintf(w.out, "watching %s from counter %d\n", p.Pipeline, mark)
+		w.state.Counters[p.Pipeline] = mark
+		w.state.ETags[p.Pipeline] = etag
+		return 0, w.save()
+	}
+	newest := page.Pipelines[0].Counter
+	if newest-watchBacklog > mark {
+		fmt.Fprintf(w.out, "no longer waiting for the runs of %s up to counter %d\n", p.Pipeline, newest-watchBacklog)
+		mark = newest - watchBacklog
+	}
+
+	// page back to the low-water mark, e.g. after some downtime
+	runs := []GocdPipelineHistory{}
+	firstPageOnly := true
+	for i := 1; ; i++ {
+		reached := false
+		for _, run := range page.Pipelines {
+			if run.Counter <= mark {
+				reached = true
+				continue
+			}
+			runs = append(runs, run)
+		}
+		if reached || page.Links.Next.Href == "" || i >= maxHistoryPages {
+			break
+		}
+		firstPageOnly = false
+		page, _, err = getGocdPipelineHistoryPageURL(w.cfg, page.Links.Next.Href, "")
+		if err != nil {
+			return 0, err
+		}
+		if page == nil {
+			break
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Counter < runs[j].Counter })
+
+	done := map[int]bool{}
+	for _, counter := range w.state.Done[p.Pipeline] {
+		done[counter] = true
+	}
+	processed := 0
+	failures := []string{}
+	for _, run := range runs {
+		if done[run.Counter] || !isStagePassed(run, p.Stage) {
+			continue
+		}
+		fmt.Fprintf(w.out, "creating release notes of %s/%d\n", p.Pipeline, run.Counter)
+		_, err := w.run(w.cfg, &QueryParams{Title: p.Title, Pipeline: p.Pipeline, Counter: run.Counter})
+		if err != nil && !isPermanentError(err) {
+			// NOTE: the ETag is kept, so that the run is retried on the next poll
+			return processed, fmt.Errorf("counter %d: %w", run.Counter, err)
+		}
+		if err != nil {
+			fmt.Fprintf(w.out, "release notes of %s/%d failed: %v\n", p.Pipeline, run.Counter, err)
+			if w.state.Failed[p.Pipeline] == nil {
+				w.state.Failed[p.Pipeline] = map[int]string{}
+			}
+			w.state.Failed[p.Pipeline][run.Counter] = err.Error()
+			failures = append(failures, fmt.Sprintf("counter %d: %v", run.Counter, err))
+		} else {
+			processed++
+		}
+		done[run.Counter] = true
+		w.state.Done[p.Pipeline] = append(w.state.Done[p.Pipeline], run.Counter)
+		err = w.save()
+		if err != nil {
+			return processed, err
+		}
+	}
+
+	// NOTE: the mark moves up over the runs done, the runs after a run still waiting to pass are kept in Done
+	for done[mark+1] {
+		mark++
+	}
+	w.state.Counters[p.Pipeline] = mark
+	counters := []int{}
+	for counter := range done {
+		if counter > mark {
+			counters = append(counters, counter)
+		}
+	}
+	sort.Ints(counters)
+	w.state.Done[p.Pipeline] = counters
+	for counter := range w.state.Failed[p.Pipeline] {
+		if counter <= newest-watchBacklog {
+			delete(w.state.Failed[p.Pipeline], counter)
+		}
+	}
+	// NOTE: a 304 on the first page only means nothing to do when the first page reaches the mark
+	if firstPageOnly {
+		w.state.ETags[p.Pipeline] = etag
+	} else {
+		delete(w.state.ETags, p.Pipeline)
+	}
+	err = w.save()
+	if err == nil && len(failures) > 0 {
+		err = fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return processed, err
+}
+
+// isPermanentError checks the release notes can't be created however often they're retried,
+// i.e. a gate stopped them (422) or the required stage didn't pass (409)
+func isPermanentError(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) &&
+		(statusErr.StatusCode == http.StatusUnprocessableEntity || statusErr.StatusCode == http.StatusConflict)
+}
+
+func isStagePassed(run GocdPipelineHistory, stage string) bool {
+	for _, s := range run.Stages {
+		if strings.EqualFold(s.Name, stage) && s.Result == "Passed" {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) load() error {
+	if w.cfg.Watch.StateFile == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(w.cfg.Watch.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(content, w.state)
+	if err != nil {
+		return fmt.Errorf("invalid watch state file %s: %w", w.cfg.Watch.StateFile, err)
+	}
+	if w.state.Counters == nil {
+		w.state.Counters = map[string]int{}
+	}
+	if w.state.Done == nil {
+		w.state.Done = map[string][]int{}
+	}
+	if w.state.Failed == nil {
+		w.state.Failed = map[string]map[int]string{}
+	}
+	if w.state.ETags == nil {
+		w.state.ETags = map[string]string{}
+	}
+	return nil
+}
+
+func (w *Watcher) save() error {
+	if w.cfg.Watch.StateFile == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(w.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	// NOTE: write and rename, so that a crash doesn't leave a partial file
+	tmp := w.cfg.Watch.StateFile + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.cfg.Watch.StateFile)
+}
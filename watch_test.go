@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newHistoryServer serves the history of the counters with their results of the deploy stage, the newest first
+func newHistoryServer(t *testing.T, results map[int]string) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/go/api/pipelines/iotic-webbing/history" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		etag := fmt.Sprintf(`"%v"`, results)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		runs := []interface{}{}
+		for counter := 1000; counter > 0; counter-- {
+			if result, ok := results[counter]; ok {
+				runs = append(runs, map[string]interface{}{
+					"name":    "iotic-webbing",
+					"counter": counter,
+					"stages":  []interface{}{map[string]interface{}{"name": "deploy", "result": result}},
+				})
+			}
+		}
+		w.Header().Set("ETag", etag)
+		json.NewEncoder(w).Encode(map[string]interface{}{"pipelines": runs})
+	}))
+	return server, &requests
+}
+
+// newPagedHistoryServer returns the runs from 105 down to 100, two per page; the 104 deploy failed and the 101 is labelled 1.42.0
+func newPagedHistoryServer(t *testing.T) (*httptest.Server, *int) {
+	requests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/go/api/pipelines/iotic-webbing/history" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		after, _ := strconv.Atoi(r.URL.Query().Get("after"))
+		if after == 0 {
+			after = 106
+		}
+		runs := []interface{}{}
+		for counter := after - 1; counter >= 100 && counter > after-3; counter-- {
+			result := "Passed"
+			if counter == 105 {
+				result = "Unknown"
+			}
+			deploy := map[string]interface{}{"name": "deploy", "result": result, "scheduled": true}
+			if counter == 104 {
+				deploy["result"] = "Failed"
+			}
+			label := fmt.Sprintf("1.41.%d", counter)
+			if counter == 101 {
+				label = "1.42.0"
+			}
+			runs = append(runs, map[string]interface{}{
+				"name":    "iotic-webbing",
+				"counter": counter,
+				"label":   label,
+				"stages": []interface{}{
+					map[string]interface{}{"name": "build", "result": "Passed", "scheduled": true},
+					deploy,
+				},
+			})
+		}
+		page := map[string]interface{}{"pipelines": runs}
+		if after-2 > 100 {
+			page["_links"] = map[string]interface{}{
+				"next": map[string]interface{}{"href": fmt.Sprintf("%s%s?after=%d", server.URL, r.URL.Path, after-2)},
+			}
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	return server, &requests
+}
+
+func TestWatcherPoll(t *testing.T) {
+	results := map[int]string{612: "Passed", 613: "Failed"}
+	server, requests := newHistoryServer(t, results)
+	defer server.Close()
+
+	cfg := NewDefaultConfig()
+	cfg.Client = server.Client()
+	cfg.GocdUrl = server.URL
+	cfg.Watch = WatchConfig{
+		Pipelines: []WatchPipeline{{Pipeline: "iotic-webbing", Stage: "Deploy", Title: "The Best Web"}},
+		StateFile: filepath.Join(t.TempDir(), "watch.json"),
+	}
+	processed := []int{}
+	failCounter := 0
+	run := func(cfg *Config, params *QueryParams) (*ReleaseResult, error) {
+		if params.Counter == failCounter {
+			return nil, fmt.Errorf("Jira is down")
+		}
+		if params.Title != "The Best Web" {
+			t.Errorf("unexpected title: %s", params.Title)
+		}
+		processed = append(processed, params.Counter)
+		return nil, nil
+	}
+	var out bytes.Buffer
+	watcher, err := newWatcher(cfg, run, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first poll starts from the latest passed run
+	found, err := watcher.poll()
+	if err != nil || found || len(processed) != 0 || watcher.state.Counters["iotic-webbing"] != 612 {
+		t.Fatalf("unexpected first poll: %v %v %v %v", found, err, processed, watcher.state.Counters)
+	}
+
+	// nothing has changed
+	found, err = watcher.poll()
+	if err != nil || found || *requests != 2 {
+		t.Fatalf("unexpected poll: %v %v %d", found, err, *requests)
+	}
+
+	// the failed run is retried on the next poll, despite the ETag
+	results[614] = "Passed"
+	results[615] = "Passed"
+	failCounter = 615
+	found, err = watcher.poll()
+	if !found || !ErrorContains(err, "iotic-webbing: counter 615: Jira is down") {
+		t.Fatalf("unexpected poll: %v %v", found, err)
+	}
+	failCounter = 0
+	found, err = watcher.poll()
+	if err != nil || !found {
+		t.Fatalf("unexpected poll: %v %v", found, err)
+	}
+	if !reflect.DeepEqual([]int{614, 615}, processed) {
+		t.Errorf("expected: %v, got: %v", []int{614, 615}, processed)
+	}
+
+	// the state survives a restart, the mark waits for the 613
+	restarted, err := newWatcher(cfg, run, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restarted.state.Counters["iotic-webbing"] != 612 || !reflect.DeepEqual([]int{614, 615}, restarted.state.Done["iotic-webbing"]) ||
+		restarted.state.ETags["iotic-webbing"] == "" {
+		t.Errorf("unexpected state: %+v", restarted.state)
+	}
+
+	// an older run passes later, e.g. after a manual approval
+	results[613] = "Passed"
+	found, err = restarted.poll()
+	if err != nil || !found {
+		t.Fatalf("unexpected poll: %v %v", found, err)
+	}
+	if !reflect.DeepEqual([]int{614, 615, 613}, processed) {
+		t.Errorf("expected: %v, got: %v", []int{614, 615, 613}, processed)
+	}
+	if restarted.state.Counters["iotic-webbing"] != 615 || len(restarted.state.Done["iotic-webbing"]) != 0 {
+		t.Errorf("unexpected state: %+v", restarted.state)
+	}
+}
+
+func TestWatcherPollRecordsFailedRuns(t *testing.T) {
+	results := map[int]string{612: "Passed"}
+	server, _ := newHistoryServer(t, results)
+	defer server.Close()
+
+	cfg := NewDefaultConfig()
+	cfg.Client = server.Client()
+	cfg.GocdUrl = server.URL
+	cfg.Watch = WatchConfig{Pipelines: []WatchPipeline{{Pipeline: "iotic-webbing", Stage: "Deploy"}}}
+	processed := []int{}
+	run := func(cfg *Config, params *QueryParams) (*ReleaseResult, error) {
+		if params.Counter == 613 {
+			return nil, &StatusError{StatusCode: http.StatusUnprocessableEntity, Err: fmt.Errorf("stopped by the gate")}
+		}
+		processed = append(processed, params.Counter)
+		return nil, nil
+	}
+	var out bytes.Buffer
+	watcher, err := newWatcher(cfg, run, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = watcher.poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the gate fails the 613 for good, the 614 is processed all the same
+	results[613] = "Passed"
+	results[614] = "Passed"
+	found, err := watcher.poll()
+	if !found || !ErrorContains(err, "iotic-webbing: counter 613: stopped by the gate") {
+		t.Fatalf("unexpected poll: %v %v", found, err)
+	}
+	if watcher.state.Failed["iotic-webbing"][613] != "stopped by the gate" || watcher.state.Counters["iotic-webbing"] != 614 {
+		t.Errorf("unexpected state: %+v", watcher.state)
+	}
+
+	// and isn't retried
+	results[615] = "Passed"
+	found, err = watcher.poll()
+	if err != nil || !found {
+		t.Fatalf("unexpected poll: %v %v", found, err)
+	}
+	if !reflect.DeepEqual([]int{614, 615}, processed) {
+		t.Errorf("expected: %v, got: %v", []int{614, 615}, processed)
+	}
+}
+
+func TestWatcherPollPagesBackToTheMark(t *testing.T) {
+	server, requests := newPagedHistoryServer(t)
+	defer server.Close()
+
+	cfg := NewDefaultConfig()
+	cfg.Client = server.Client()
+	cfg.GocdUrl = server.URL
+	cfg.Watch = WatchConfig{Pipelines: []WatchPipeline{{Pipeline: "iotic-webbing", Stage: "Deploy"}}}
+	processed := []int{}
+	run := func(cfg *Config, params *QueryParams) (*ReleaseResult, error) {
+		processed = append(processed, params.Counter)
+		return nil, nil
+	}
+	var out bytes.Buffer
+	watcher, err := newWatcher(cfg, run, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher.state.Counters["iotic-webbing"] = 100
+
+	found, err := watcher.poll()
+	if err != nil || !found {
+		t.Fatalf("unexpected poll: %v %v", found, err)
+	}
+	// NOTE: the 104 failed and the 105 is running, the mark waits for the 104
+	if !reflect.DeepEqual([]int{101, 102, 103}, processed) || *requests != 3 || watcher.state.Counters["iotic-webbing"] != 103 {
+		t.Errorf("unexpected poll: %v after %d requests, state: %+v", processed, *requests, watcher.state)
+	}
+}
+
+func TestWatcherInterval(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Watch = WatchConfig{Interval: time.Minute, MaxInterval: 5 * time.Minute}
+	watcher := &Watcher{cfg: cfg}
+
+	tests := []struct {
+		name    string
+		current time.Duration
+		found   bool
+		err     error
+		want    time.Duration
+	}{
+		{name: "start", current: 0, want: time.Minute},
+		{name: "nothing new", current: time.Minute, want: 2 * time.Minute},
+		{name: "error", current: 2 * time.Minute, err: fmt.Errorf("timeout"), want: 4 * time.Minute},
+		{name: "max", current: 4 * time.Minute, want: 5 * time.Minute},
+		{name: "new run", current: 5 * time.Minute, found: true, want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := watcher.interval(tt.current, tt.found, tt.err); got != tt.want {
+				t.Errorf("expected: %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
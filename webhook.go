@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const defaultSignatureHeader = "X-Signature-256"
+
+// WebhookConfig sends the release data rendered by a text/template
+// to any HTTP endpoint, e.g. a Microsoft Teams incoming webhook
+type WebhookConfig struct {
+	Name     string
+	URL      string
+	Pipeline string
+	// Template is a path to a text/template file,
+	// see templates/teams-adaptive-card.json.tmpl
+	Template    string
+	ContentType string
+	Headers     map[string]string
+	// SecretName is the name of the secret used to sign the body with HMAC-SHA256, e.g. teamswebhooksecret,
+	// the signature is sent as "sha256=<hex>" in the SignatureHeader
+	SecretName string
+	// Secret is read from the secret named SecretName, see getAPISecret
+	Secret          string `mapstructure:"-"`
+	SignatureHeader string
+	Retries         int
+	RetryDelay      time.Duration
+}
+
+// WebhookPublisher sends the release notes to all the configured webhooks
+type WebhookPublisher struct{}
+
+func (p *WebhookPublisher) Name() string {
+	return "webhook"
+}
+
+func (p *WebhookPublisher) Publish(cfg *Config, release *Release) error {
+	data := newReleaseData(release)
+	for _, webhook := range cfg.Webhooks {
+		if !matchPipeline(webhook.Pipeline, release.Pipeline) {
+			continue
+		}
+		body, err := renderWebhookTemplate(webhook, data)
+		if err != nil {
+			return err
+		}
+		err = postWebhook(cfg, webhook, body)
+		if err != nil {
+			return fmt.Errorf("webhook %s: %w", webhook.Name, err)
+		}
+	}
+	return nil
+}
+
+func renderWebhookTemplate(webhook WebhookConfig, data *ReleaseData) ([]byte, error) {
+	text, err := ioutil.ReadFile(webhook.Template)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(webhook.Name).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return nil, err
+	}
+	body := buf.Bytes()
+	if strings.Contains(getWebhookContentType(webhook), "json") && !json.Valid(body) {
+		return nil, fmt.Errorf("webhook %s: template %s did not render valid JSON", webhook.Name, webhook.Template)
+	}
+	return body, nil
+}
+
+func getWebhookContentType(webhook WebhookConfig) string {
+	if webhook.ContentType == "" {
+		return "application/json"
+	}
+	return webhook.ContentType
+}
+
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func postWebhook(cfg *Config, webhook WebhookConfig, body []byte) error {
+	delay := webhook.RetryDelay
+	if delay == 0 {
+		delay = time.Second
+	}
+
+	var err error
+	for attempt := 0; attempt <= webhook.Retries; attempt++ {
+		if attempt > 0 {
+			log.Printf("Retrying webhook %s in %v: %v", webhook.Name, delay, err)
+			time.Sleep(delay)
+			delay *= 2
+		}
+		var retry bool
+		retry, err = doWebhookRequest(cfg, webhook, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// doWebhookRequest sends the body once,
+// it returns whether a failed request can be retried
+func doWebhookRequest(cfg *Config, webhook WebhookConfig, body []byte) (bool, error) {
+
+	log.Printf("Calling webhook %s", webhook.Name)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", getWebhookContentType(webhook))
+	for k, v := range webhook.Headers {
+		req.Header.Set(k, v)
+	}
+	if webhook.Secret != "" {
+		header := webhook.SignatureHeader
+		if header == "" {
+			header = defaultSignatureHeader
+		}
+		req.Header.Set(header, signWebhookBody(webhook.Secret, body))
+	}
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	response, _ := ioutil.ReadAll(resp.Body)
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("%d %s", resp.StatusCode, string(response))
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRenderTeamsAdaptiveCardTemplate(t *testing.T) {
+	webhook := WebhookConfig{Name: "teams", Template: "./templates/teams-adaptive-card.json.tmpl"}
+
+	body, err := renderWebhookTemplate(webhook, newReleaseData(newTestRelease()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var card struct {
+		Attachments []struct {
+			Content struct {
+				Body []struct {
+					Text string `json:"text"`
+				} `json:"body"`
+				Actions []struct {
+					URL string `json:"url"`
+				} `json:"actions"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	err = json.Unmarshal(body, &card)
+	if err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, body)
+	}
+	content := card.Attachments[0].Content
+	if content.Body[0].Text != "The Best Web 2.0.390" {
+		t.Errorf("unexpected title: %v", content.Body[0].Text)
+	}
+	if content.Body[5].Text != "- Impr1\n- [RPCStatus](https://example.com/rpc)" {
+		t.Errorf("unexpected bullet points: %v", content.Body[5].Text)
+	}
+	if content.Actions[0].URL != "https://example.com/wiki/spaces/RN/blog/2021/03/26/539492380" {
+		t.Errorf("unexpected link: %v", content.Actions[0].URL)
+	}
+}
+
+func TestWebhookPublisherSignsAndRetries(t *testing.T) {
+	requests := 0
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ = ioutil.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	cfg := NewDefaultConfig()
+	cfg.Client = server.Client()
+	cfg.Webhooks = []WebhookConfig{
+		{
+			Name:       "ops",
+			Pipeline:   "*",
+			URL:        server.URL,
+			Template:   "./templates/teams-adaptive-card.json.tmpl",
+			Headers:    map[string]string{"x-source": "release-notes"},
+			Secret:     "s3cr3t",
+			Retries:    2,
+			RetryDelay: time.Millisecond,
+		},
+		{Name: "other", Pipeline: "other-pipeline", URL: server.URL + "/other"},
+	}
+
+	err := (&WebhookPublisher{}).Publish(cfg, newTestRelease())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("expected the webhook to be retried once, got %d requests", requests)
+	}
+	if header.Get("X-Source") != "release-notes" {
+		t.Errorf("missing custom header: %v", header)
+	}
+	if header.Get(defaultSignatureHeader) != signWebhookBody("s3cr3t", body) {
+		t.Errorf("unexpected signature: %v", header.Get(defaultSignatureHeader))
+	}
+}
+
+func TestWebhookPublisherDoesNotRetryClientErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	cfg := NewDefaultConfig()
+	cfg.Client = server.Client()
+	cfg.Webhooks = []WebhookConfig{
+		{Name: "ops", Pipeline: "*", URL: server.URL, Template: "./templates/teams-adaptive-card.json.tmpl", Retries: 3},
+	}
+
+	err := (&WebhookPublisher{}).Publish(cfg, newTestRelease())
+	if !ErrorContains(err, "webhook ops: 400") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected a single request, got %d", requests)
+	}
+}
+
+func TestRenderWebhookTemplateJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{name: "object", template: `{"version": "{{ .Version }}"}`},
+		{name: "array", template: `[{"version": "{{ .Version }}"}]`},
+		{name: "invalid", template: `{"version": {{ .Version }}}`, wantErr: "did not render valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "webhook.json.tmpl")
+			err := os.WriteFile(filename, []byte(tt.template), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = renderWebhookTemplate(WebhookConfig{Name: "ops", Template: filename}, newReleaseData(newTestRelease()))
+			if !ErrorContains(err, tt.wantErr) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}